@@ -37,12 +37,14 @@ const (
 const (
 	flagAPIKey          = "api_key"
 	flagIgnoreTLSErrors = "ignore_tls_errors"
+	flagTrace           = "trace"
 )
 
 func Configure() {
 	fs := pflag.NewFlagSet("", pflag.ExitOnError)
 	fs.String(flagAPIKey, "", "Cloud One API Key")
 	fs.Bool(flagIgnoreTLSErrors, false, "Ignore all TLS errors")
+	fs.Bool(flagTrace, false, "Log all API requests")
 	err := fs.Parse(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
@@ -71,7 +73,11 @@ func Configure() {
 }
 
 func DetectEntryPoint(apikey string) (string, error) {
-	region, err := cone.NewClient(apikey).CurrentAPIKeyRegion(context.TODO())
+	client := cone.NewClient(apikey)
+	if viper.GetBool(flagTrace) {
+		client.SetTracer(c1ews.NewTracer(false))
+	}
+	region, err := client.CurrentAPIKeyRegion(context.TODO())
 	if err == nil {
 		return c1ews.EntryPoint(region), nil
 	}
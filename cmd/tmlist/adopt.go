@@ -0,0 +1,134 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  adopt.go - mark lists as managed by TMList instance
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func AdoptFlags(fs *pflag.FlagSet) {
+	fs.StringSlice(flagList, nil, "Name of the list to adopt (can be repeated)")
+}
+
+// AdoptCommand - add ownership marker of the instance to given lists
+func AdoptCommand(args []string) (exitCode int) {
+	instance := viper.GetString(flagInstance)
+	if instance == "" {
+		log.Printf("%s parameter is missing", flagInstance)
+		return RCCommandLine
+	}
+	names := viper.GetStringSlice(flagList)
+	if len(names) == 0 {
+		log.Printf("%s parameter is missing", flagList)
+		return RCCommandLine
+	}
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	// All lists are checked before any of them is modified
+	kinds := SelectedKinds()
+	selected := make([][]c1ews.ListResponse, len(kinds))
+	found := make(map[string]bool)
+	for i, kind := range kinds {
+		lists, err := ws.ListLists(context.TODO(), kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCAPIError
+		}
+		for _, l := range lists {
+			for _, name := range names {
+				if l.Name == name {
+					found[name] = true
+					selected[i] = append(selected[i], l)
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		if !found[name] {
+			log.Printf("%s: %v", name, process.ErrListNotFound)
+			return RCListNotFound
+		}
+	}
+	refused := false
+	for i, kind := range kinds {
+		var ok bool
+		selected[i], ok = ListsToAdopt(kind, instance, selected[i])
+		refused = refused || !ok
+	}
+	if refused {
+		return RCOwnedByOther
+	}
+	for i, kind := range kinds {
+		if rc := AdoptLists(ws, kind, instance, selected[i]); rc != 0 {
+			return rc
+		}
+	}
+	return 0
+}
+
+// ListsToAdopt - return lists that do not have ownership marker of the
+// instance yet. Return false if any list is managed by other instance and
+// owner change is not forced
+func ListsToAdopt(kind *c1ews.ListKind, instance string, lists []c1ews.ListResponse) ([]c1ews.ListResponse, bool) {
+	var result []c1ews.ListResponse
+	ok := true
+	for _, l := range lists {
+		owner := process.ManagedBy(&l)
+		if owner == instance {
+			log.Printf("%s: %s is already managed by %s", kind.Name, l.Name, instance)
+			continue
+		}
+		if owner != "" {
+			if !viper.GetBool(flagForce) {
+				log.Printf("%s: %s is managed by %s. Use --force option to change owner to %s", kind.Name, l.Name, owner, instance)
+				ok = false
+				continue
+			}
+			log.Printf("%s: %s is managed by %s. Change owner to %s (forced)", kind.Name, l.Name, owner, instance)
+		}
+		result = append(result, l)
+	}
+	return result, ok
+}
+
+// AdoptLists - set ownership marker of given lists
+func AdoptLists(ws *c1ews.Client, kind *c1ews.ListKind, instance string, lists []c1ews.ListResponse) int {
+	if viper.GetBool(flagDryRun) {
+		for _, l := range lists {
+			log.Printf("%s: adopt %s", kind.Name, l.Name)
+		}
+		return 0
+	}
+	if rc := Backup(viper.GetString(flagBackupDir), ws.Host, kind, lists); rc != 0 {
+		return rc
+	}
+	for i := range lists {
+		log.Printf("%s: adopt %s", kind.Name, lists[i].Name)
+		l := lists[i]
+		process.SetManagedBy(&l, instance)
+		if _, err := ws.ModifyList(context.TODO(), kind, l.ID, process.ListFromResponse(&l)); err != nil {
+			log.Printf("%s: %s: %v", kind.Name, l.Name, err)
+			return RCAPIError
+		}
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  adopt_test.go - tests for ownership of lists
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/viper"
+)
+
+func TestAdopt(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(flagInstance, "team1")
+	viper.Set(flagUnmarked, UnmarkedRefuse)
+	before := s.Lists(c1ews.DirectoryLists)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != RCUnmarked {
+		t.Errorf("return code %d instead of %d", rc, RCUnmarked)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("unmarked list was modified")
+	}
+	viper.Set(flagList, []string{"Database Servers"})
+	if rc := AdoptCommand(nil); rc != 0 {
+		t.Fatalf("adopt return code %d", rc)
+	}
+	if owner := process.ManagedBy(s.FindList(c1ews.DirectoryLists, "Database Servers")); owner != "team1" {
+		t.Errorf("owner is %q", owner)
+	}
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Errorf("return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected := []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	if !reflect.DeepEqual(before[0], *s.FindList(c1ews.DirectoryLists, "Windows")) {
+		t.Errorf("list without marker was modified")
+	}
+}
+
+func TestAdoptOtherOwner(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	list := s.FindList(c1ews.DirectoryLists, "Database Servers")
+	process.SetManagedBy(list, "team2")
+	s.SetList(c1ews.DirectoryLists, *list)
+	viper.Set(flagInstance, "team1")
+	viper.Set(flagList, []string{"Database Servers", "Windows"})
+	before := s.Lists(c1ews.DirectoryLists)
+	if rc := AdoptCommand(nil); rc != RCOwnedByOther {
+		t.Errorf("adopt return code %d instead of %d", rc, RCOwnedByOther)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists were modified")
+	}
+	viper.Set(flagForce, true)
+	if rc := AdoptCommand(nil); rc != 0 {
+		t.Fatalf("forced adopt return code %d", rc)
+	}
+	if owner := process.ManagedBy(s.FindList(c1ews.DirectoryLists, "Database Servers")); owner != "team1" {
+		t.Errorf("owner is %q", owner)
+	}
+}
+
+func TestAdoptSeveralKinds(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	list := s.FindList(c1ews.FileExtensionLists, "Logs")
+	process.SetManagedBy(list, "team2")
+	s.SetList(c1ews.FileExtensionLists, *list)
+	viper.Set(flagInstance, "team1")
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	viper.Set(c1ews.FileExtensionLists.ID, true)
+	dirBefore := s.Lists(c1ews.DirectoryLists)
+	viper.Set(flagList, []string{"Windows", "Logs"})
+	if rc := AdoptCommand(nil); rc != RCOwnedByOther {
+		t.Errorf("adopt return code %d instead of %d", rc, RCOwnedByOther)
+	}
+	viper.Set(flagList, []string{"Windows", "Missing"})
+	if rc := AdoptCommand(nil); rc != RCListNotFound {
+		t.Errorf("adopt return code %d instead of %d", rc, RCListNotFound)
+	}
+	if !reflect.DeepEqual(dirBefore, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists of other kind were modified")
+	}
+}
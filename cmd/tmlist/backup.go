@@ -0,0 +1,185 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  backup.go - save lists before modification and restore them
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagSnapshot = "snapshot"
+	flagList     = "list"
+)
+
+// Snapshot - content of lists before modification
+type Snapshot struct {
+	Created time.Time            `json:"created"`
+	Address string               `json:"address"`
+	Kind    string               `json:"kind"`
+	Lists   []c1ews.ListResponse `json:"lists"`
+}
+
+// SaveSnapshot - write lists to new timestamped file in given directory.
+// Return file name
+func SaveSnapshot(dir string, address string, kind *c1ews.ListKind, lists []c1ews.ListResponse) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	snapshot := &Snapshot{
+		Created: time.Now().UTC(),
+		Address: address,
+		Kind:    kind.ID,
+		Lists:   lists,
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.json", snapshot.Created.Format("20060102-150405.000000000"), kind.ID)
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, data, 0600)
+}
+
+// LoadSnapshot - read snapshot from file
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+func RestoreFlags(fs *pflag.FlagSet) {
+	fs.String(flagSnapshot, "", "Snapshot file to restore lists from")
+	fs.StringSlice(flagList, nil, "Restore only list with given name (can be repeated)")
+}
+
+// RestoreCommand - put content of lists from snapshot back. Snapshot of
+// other manager is restored only if forced; lists are matched by ID and name
+func RestoreCommand(args []string) (exitCode int) {
+	path := viper.GetString(flagSnapshot)
+	if path == "" {
+		log.Printf("%s parameter is missing", flagSnapshot)
+		return RCCommandLine
+	}
+	snapshot, err := LoadSnapshot(path)
+	if err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	kind := c1ews.FindListKind(snapshot.Kind)
+	if kind == nil {
+		log.Printf("%s: unknown list kind", snapshot.Kind)
+		return RCOther
+	}
+	lists, err := FilterLists(snapshot.Lists, viper.GetStringSlice(flagList))
+	if err != nil {
+		log.Print(err)
+		return RCListNotFound
+	}
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	if snapshot.Address != ws.Host {
+		if !viper.GetBool(flagForce) {
+			log.Printf("snapshot was created for %s and can not be restored to %s. Use --force option to restore anyway", snapshot.Address, ws.Host)
+			return RCDrift
+		}
+		log.Printf("snapshot was created for %s. Restore to %s (forced)", snapshot.Address, ws.Host)
+	}
+	var current []c1ews.ListResponse
+	for _, l := range lists {
+		c, err := ws.DescribeList(context.TODO(), kind, l.ID)
+		if err != nil {
+			log.Printf("%s: %s: %v", kind.Name, l.Name, err)
+			return RCAPIError
+		}
+		if c.Name != l.Name {
+			// List was deleted or renamed, so ID belongs to other list now
+			log.Printf("%s: %s: list with ID %d is %s now", kind.Name, l.Name, l.ID, c.Name)
+			return RCDrift
+		}
+		current = append(current, *c)
+	}
+	dryRun := viper.GetBool(flagDryRun)
+	if !dryRun {
+		if rc := Backup(viper.GetString(flagBackupDir), ws.Host, kind, current); rc != 0 {
+			return rc
+		}
+	}
+	for i := range lists {
+		log.Printf("%s: restore %s", kind.Name, lists[i].Name)
+		if dryRun {
+			continue
+		}
+		_, err := ws.ModifyList(context.TODO(), kind, lists[i].ID, process.ListFromResponse(&lists[i]))
+		if err != nil {
+			log.Printf("%s: %s: %v", kind.Name, lists[i].Name, err)
+			return RCAPIError
+		}
+	}
+	return 0
+}
+
+// FilterLists - return lists with given names or all lists if no names are given
+func FilterLists(lists []c1ews.ListResponse, names []string) ([]c1ews.ListResponse, error) {
+	if len(names) == 0 {
+		return lists, nil
+	}
+	var result []c1ews.ListResponse
+	for _, name := range names {
+		found := false
+		for _, l := range lists {
+			if l.Name == name {
+				result = append(result, l)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s: %w", name, process.ErrListNotFound)
+		}
+	}
+	return result, nil
+}
+
+// Backup - save lists to backup directory if it is configured
+func Backup(dir string, address string, kind *c1ews.ListKind, lists []c1ews.ListResponse) int {
+	if dir == "" || len(lists) == 0 {
+		return 0
+	}
+	path, err := SaveSnapshot(dir, address, kind, lists)
+	if err != nil {
+		log.Printf("%s: backup: %v", kind.Name, err)
+		return RCOther
+	}
+	log.Printf("%s: %d lists saved to %s", kind.Name, len(lists), path)
+	return 0
+}
@@ -0,0 +1,98 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  backup_test.go - tests for backup and restore
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func TestBackupRestore(t *testing.T) {
+	s, ws := newServer(t)
+	before := s.Lists(c1ews.DirectoryLists)
+	dir := t.TempDir()
+	r := NewRunner(ws)
+	r.BackupDir = dir
+	if rc := r.ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("backup files: %v, %v", files, err)
+	}
+	snapshot, err := LoadSnapshot(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Lists) != 3 {
+		t.Errorf("%d lists in snapshot instead of 3", len(snapshot.Lists))
+	}
+
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(flagSnapshot, files[0])
+	viper.Set(flagList, []string{"Database Servers"})
+	viper.Set(flagBackupDir, dir)
+	if rc := RestoreCommand(nil); rc != 0 {
+		t.Fatalf("restore return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers")
+	if !reflect.DeepEqual(*actual, before[2]) {
+		t.Errorf("%v is not equal to %v", *actual, before[2])
+	}
+	if reflect.DeepEqual(s.Lists(c1ews.DirectoryLists), before) {
+		t.Errorf("all lists are restored")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("restore did not backup current state: %d files", len(entries))
+	}
+
+	viper.Set(flagList, []string{"Missing"})
+	if rc := RestoreCommand(nil); rc != RCListNotFound {
+		t.Errorf("return code %d instead of %d", rc, RCListNotFound)
+	}
+
+	viper.Set(flagList, []string{"Database Servers"})
+	viper.Set(flagAddress, "https://other.example.com")
+	if rc := RestoreCommand(nil); rc != RCDrift {
+		t.Errorf("snapshot of %s is restored to other manager: return code %d", snapshot.Address, rc)
+	}
+}
+
+func TestRestoreRenamed(t *testing.T) {
+	s, ws := newServer(t)
+	lists := s.Lists(c1ews.DirectoryLists)
+	dir := t.TempDir()
+	if rc := Backup(dir, ws.Host, c1ews.DirectoryLists, lists); rc != 0 {
+		t.Fatalf("backup return code %d", rc)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("backup files: %v, %v", files, err)
+	}
+	renamed := lists[0]
+	renamed.Name = "Renamed"
+	renamed.Items = []string{"C:\\Other"}
+	s.SetList(c1ews.DirectoryLists, renamed)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(flagSnapshot, files[0])
+	viper.Set(flagBackupDir, "")
+	if rc := RestoreCommand(nil); rc != RCDrift {
+		t.Errorf("return code %d instead of %d", rc, RCDrift)
+	}
+	if actual := s.FindList(c1ews.DirectoryLists, "Renamed"); !reflect.DeepEqual(*actual, renamed) {
+		t.Errorf("other list is overwritten: %v", *actual)
+	}
+}
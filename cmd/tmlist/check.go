@@ -0,0 +1,121 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  check.go - report lists that are out of sync without modifying them
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const flagReport = "report"
+
+// CheckReport - result of check command
+type CheckReport struct {
+	Created time.Time    `json:"created"`
+	Address string       `json:"address"`
+	InSync  bool         `json:"in_sync"`
+	Kinds   []*KindCheck `json:"kinds"`
+}
+
+// KindCheck - state of lists of one kind
+type KindCheck struct {
+	Kind string `json:"kind"`
+	// OutOfSync - lists that differ from their desired state
+	OutOfSync []process.ListDiff `json:"out_of_sync,omitempty"`
+	// ManualEdits - generated lists edited in console since last run
+	ManualEdits []process.ManualEdit `json:"manual_edits,omitempty"`
+	// Error - broken include or other reason desired state can not be computed
+	Error string `json:"error,omitempty"`
+}
+
+// InSync - return true if lists of this kind do not require any changes
+func (k *KindCheck) InSync() bool {
+	return len(k.OutOfSync) == 0 && len(k.ManualEdits) == 0 && k.Error == ""
+}
+
+func CheckFlags(fs *pflag.FlagSet) {
+	fs.String(flagReport, "", "JSON report file name (default - standard output)")
+}
+
+// CheckCommand - compute desired state of selected kinds of lists and report
+// lists that are out of sync. Never modifies lists
+func CheckCommand(args []string) (exitCode int) {
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	report := &CheckReport{
+		Created: time.Now().UTC(),
+		Address: ws.Host,
+		InSync:  true,
+	}
+	for _, kind := range SelectedKinds() {
+		lists, err := ws.ListLists(context.TODO(), kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCAPIError
+		}
+		p, err := NewKindProcess(kind, lists)
+		if err != nil {
+			log.Print(err)
+			return SetupReturnCode(err)
+		}
+		check := &KindCheck{Kind: kind.ID}
+		if err := p.Process(); err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			check.Error = err.Error()
+		} else {
+			check.OutOfSync = p.Diff()
+			check.ManualEdits = p.ManualEdits()
+		}
+		for _, diff := range check.OutOfSync {
+			log.Printf("%s: %s is out of sync", kind.Name, diff.Name)
+		}
+		ReportManualEdits(kind, p)
+		report.InSync = report.InSync && check.InSync()
+		report.Kinds = append(report.Kinds, check)
+	}
+	if err := WriteReport(viper.GetString(flagReport), report); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	if !report.InSync {
+		return RCOutOfSync
+	}
+	return 0
+}
+
+// WriteReport - save check report to file or standard output if path is empty
+func WriteReport(path string, report *CheckReport) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
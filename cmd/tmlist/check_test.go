@@ -0,0 +1,81 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  check_test.go - tests for check command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func runCheck(t *testing.T) (int, *CheckReport) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.json")
+	viper.Set(flagReport, path)
+	rc := CheckCommand(nil)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report CheckReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	return rc, &report
+}
+
+func TestCheck(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	before := s.Lists(c1ews.DirectoryLists)
+	rc, report := runCheck(t)
+	if rc != RCOutOfSync {
+		t.Errorf("return code %d instead of %d", rc, RCOutOfSync)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("check modified lists")
+	}
+	if report.InSync || len(report.Kinds) != 1 || len(report.Kinds[0].OutOfSync) != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("process return code %d", rc)
+	}
+	rc, report = runCheck(t)
+	if rc != 0 || !report.InSync {
+		t.Errorf("lists are out of sync after processing: %d, %+v", rc, report.Kinds[0])
+	}
+}
+
+func TestCheckBrokenInclude(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("process return code %d", rc)
+	}
+	s.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:        "Broken",
+		Description: "Include: Missing",
+	})
+	rc, report := runCheck(t)
+	if rc != RCOutOfSync {
+		t.Errorf("return code %d instead of %d", rc, RCOutOfSync)
+	}
+	if report.InSync || report.Kinds[0].Error == "" {
+		t.Errorf("broken include is not reported: %+v", report.Kinds[0])
+	}
+}
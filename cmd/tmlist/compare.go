@@ -0,0 +1,243 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  compare.go - report differences between lists of two managers
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagLeft   = "left"
+	flagRight  = "right"
+	flagFormat = "format"
+)
+
+// Compare output formats
+const (
+	CompareText = "text"
+	CompareJSON = "json"
+)
+
+// Comparison - differences between lists of two managers
+type Comparison struct {
+	Left  string            `json:"left"`
+	Right string            `json:"right"`
+	Kinds []*KindComparison `json:"kinds"`
+}
+
+// KindComparison - differences between lists of one kind
+type KindComparison struct {
+	Kind      string           `json:"kind"`
+	OnlyLeft  []string         `json:"only_left,omitempty"`
+	OnlyRight []string         `json:"only_right,omitempty"`
+	Different []ListComparison `json:"different,omitempty"`
+}
+
+// ListComparison - differences between lists with the same name
+type ListComparison struct {
+	Name string `json:"name"`
+	// OnlyLeft - items of left list missing in right one
+	OnlyLeft  []string `json:"only_left,omitempty"`
+	OnlyRight []string `json:"only_right,omitempty"`
+	// IncludesLeft and IncludesRight are set only if includes differ
+	IncludesLeft  []string `json:"includes_left,omitempty"`
+	IncludesRight []string `json:"includes_right,omitempty"`
+}
+
+// Equal - return true if no differences were found
+func (c *KindComparison) Equal() bool {
+	return len(c.OnlyLeft)+len(c.OnlyRight)+len(c.Different) == 0
+}
+
+// Equal - return true if no differences were found
+func (c *Comparison) Equal() bool {
+	for _, kind := range c.Kinds {
+		if !kind.Equal() {
+			return false
+		}
+	}
+	return true
+}
+
+func CompareFlags(fs *pflag.FlagSet) {
+	fs.String(flagLeft, "", "Profile of first manager")
+	fs.String(flagRight, "", "Profile of second manager")
+	fs.String(flagFormat, CompareText, "Output format: text or json")
+}
+
+// CompareCommand - report differences between lists of selected kinds of
+// two profiles managers
+func CompareCommand(args []string) int {
+	left := viper.GetString(flagLeft)
+	right := viper.GetString(flagRight)
+	if left == "" || right == "" {
+		log.Printf("both %s and %s parameters should be provided", flagLeft, flagRight)
+		return RCCommandLine
+	}
+	format := viper.GetString(flagFormat)
+	if format != CompareText && format != CompareJSON {
+		log.Printf("%s: unknown format", format)
+		return RCCommandLine
+	}
+	kinds := SelectedKinds()
+	leftLists, rc := FetchProfile(left, kinds)
+	if rc != 0 {
+		return rc
+	}
+	rightLists, rc := FetchProfile(right, kinds)
+	if rc != 0 {
+		return rc
+	}
+	comparison := &Comparison{Left: left, Right: right}
+	for i, kind := range kinds {
+		comparison.Kinds = append(comparison.Kinds, CompareLists(kind, leftLists[i], rightLists[i]))
+	}
+	if err := WriteComparison(os.Stdout, comparison, format); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	if !comparison.Equal() {
+		return RCDifferent
+	}
+	return 0
+}
+
+// FetchProfile - return lists of given kinds of given profile manager in
+// order of kinds
+func FetchProfile(profile string, kinds []*c1ews.ListKind) ([][]c1ews.ListResponse, int) {
+	result := make([][]c1ews.ListResponse, len(kinds))
+	err := WithProfile(profile, func() error {
+		ws, err := NewClient()
+		if err != nil {
+			return err
+		}
+		defer SaveClient(ws)
+		for i, kind := range kinds {
+			lists, err := ws.ListLists(context.TODO(), kind)
+			if err != nil {
+				return fmt.Errorf("%s: %w", kind.Name, err)
+			}
+			result[i] = lists
+		}
+		return nil
+	})
+	if errors.Is(err, ErrUnknownProfile) {
+		log.Print(err)
+		return nil, RCCommandLine
+	}
+	if err != nil {
+		log.Printf("%s: %v", profile, err)
+		return nil, RCAPIError
+	}
+	return result, 0
+}
+
+// CompareLists - return differences between lists of given kind. Lists are
+// matched by name. Order of items and includes is not significant
+func CompareLists(kind *c1ews.ListKind, left, right []c1ews.ListResponse) *KindComparison {
+	result := &KindComparison{Kind: kind.ID}
+	rightByName := make(map[string]*c1ews.ListResponse)
+	for i := range right {
+		rightByName[right[i].Name] = &right[i]
+	}
+	leftNames := make(map[string]bool)
+	for i := range left {
+		l := &left[i]
+		leftNames[l.Name] = true
+		r, found := rightByName[l.Name]
+		if !found {
+			result.OnlyLeft = append(result.OnlyLeft, l.Name)
+			continue
+		}
+		diff := ListComparison{
+			Name:      l.Name,
+			OnlyLeft:  process.Subtract(l.Items, r.Items),
+			OnlyRight: process.Subtract(r.Items, l.Items),
+		}
+		includesLeft := process.RemoveDuplicates(process.Includes(l))
+		includesRight := process.RemoveDuplicates(process.Includes(r))
+		if !reflect.DeepEqual(includesLeft, includesRight) {
+			diff.IncludesLeft = includesLeft
+			diff.IncludesRight = includesRight
+		}
+		if len(diff.OnlyLeft)+len(diff.OnlyRight)+len(diff.IncludesLeft)+len(diff.IncludesRight) > 0 {
+			result.Different = append(result.Different, diff)
+		}
+	}
+	for _, r := range right {
+		if !leftNames[r.Name] {
+			result.OnlyRight = append(result.OnlyRight, r.Name)
+		}
+	}
+	sort.Strings(result.OnlyLeft)
+	sort.Strings(result.OnlyRight)
+	sort.Slice(result.Different, func(i, j int) bool {
+		return result.Different[i].Name < result.Different[j].Name
+	})
+	return result
+}
+
+// WriteComparison - output comparison in given format
+func WriteComparison(w io.Writer, c *Comparison, format string) error {
+	if format == CompareJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(c)
+	}
+	var sb strings.Builder
+	for _, kind := range c.Kinds {
+		if kind.Equal() {
+			continue
+		}
+		name := kind.Kind
+		if listKind := c1ews.FindListKind(kind.Kind); listKind != nil {
+			name = listKind.Name
+		}
+		fmt.Fprintf(&sb, "%s\n", name)
+		for _, name := range kind.OnlyLeft {
+			fmt.Fprintf(&sb, "  %s: only in %s\n", name, c.Left)
+		}
+		for _, name := range kind.OnlyRight {
+			fmt.Fprintf(&sb, "  %s: only in %s\n", name, c.Right)
+		}
+		for _, diff := range kind.Different {
+			fmt.Fprintf(&sb, "  %s: differs\n", diff.Name)
+			for _, item := range diff.OnlyLeft {
+				fmt.Fprintf(&sb, "    < %s\n", item)
+			}
+			for _, item := range diff.OnlyRight {
+				fmt.Fprintf(&sb, "    > %s\n", item)
+			}
+			if len(diff.IncludesLeft)+len(diff.IncludesRight) > 0 {
+				fmt.Fprintf(&sb, "    includes in %s: %s\n", c.Left, strings.Join(diff.IncludesLeft, ", "))
+				fmt.Fprintf(&sb, "    includes in %s: %s\n", c.Right, strings.Join(diff.IncludesRight, ", "))
+			}
+		}
+	}
+	if c.Equal() {
+		fmt.Fprintf(&sb, "Lists of %s and %s are equal\n", c.Left, c.Right)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
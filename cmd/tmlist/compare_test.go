@@ -0,0 +1,93 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  compare_test.go - tests for compare command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func TestCompareLists(t *testing.T) {
+	left := []c1ews.ListResponse{
+		{ID: 1, Name: "Windows", Items: []string{"a", "b"}},
+		{ID: 2, Name: "Servers", Description: "Include: Windows\nInclude: SQL", Items: []string{"a", "b", "c"}},
+		{ID: 3, Name: "Staging Only"},
+	}
+	right := []c1ews.ListResponse{
+		{ID: 10, Name: "Servers", Description: "Include: SQL\nInclude: Windows\nSync-Hash: 123", Items: []string{"c", "b", "a"}},
+		{ID: 11, Name: "Windows", Description: "Include: Base", Items: []string{"b", "d"}},
+		{ID: 12, Name: "Prod Only"},
+	}
+	actual := CompareLists(c1ews.DirectoryLists, left, right)
+	expected := &KindComparison{
+		Kind:      c1ews.DirectoryLists.ID,
+		OnlyLeft:  []string{"Staging Only"},
+		OnlyRight: []string{"Prod Only"},
+		Different: []ListComparison{{
+			Name:          "Windows",
+			OnlyLeft:      []string{"a"},
+			OnlyRight:     []string{"d"},
+			IncludesLeft:  []string{},
+			IncludesRight: []string{"Base"},
+		}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%+v is not equal to %+v", actual, expected)
+	}
+	if !CompareLists(c1ews.DirectoryLists, left, left).Equal() {
+		t.Errorf("same lists are reported as different")
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	c := &Comparison{Left: "staging", Right: "prod"}
+	for _, kind := range []*c1ews.ListKind{c1ews.FileExtensionLists, c1ews.PortLists} {
+		c.Kinds = append(c.Kinds, &KindComparison{Kind: kind.ID})
+	}
+	var buf bytes.Buffer
+	if err := WriteComparison(&buf, c, CompareText); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "are equal") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	c.Kinds[0].OnlyLeft = []string{"Windows"}
+	buf.Reset()
+	if err := WriteComparison(&buf, c, CompareText); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "File Extension Lists\n  Windows: only in staging") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestFetchProfile(t *testing.T) {
+	s, _ := newServer(t)
+	resetProfiles(t)
+	viper.Set(profilesKey, map[string]any{
+		"staging": map[string]any{flagAddress: s.URL, flagAPIKey: s.APIKey},
+	})
+	kinds := []*c1ews.ListKind{c1ews.FileExtensionLists}
+	lists, rc := FetchProfile("staging", kinds)
+	if rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if len(lists) != 1 || len(lists[0]) == 0 {
+		t.Errorf("unexpected lists: %v", lists)
+	}
+	if s.Requests() != 1 {
+		t.Errorf("%d requests instead of 1", s.Requests())
+	}
+}
@@ -0,0 +1,68 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  export.go - save all lists to directory of YAML files
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/listfile"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// flagExportDir - configuration key of export directory. Command line
+// option is --dir, but "dir" key is used by directory lists option
+const flagExportDir = "export_dir"
+
+func ExportFlags(fs *pflag.FlagSet) {
+	fs.String("dir", "lists", "Directory to save lists to")
+	if err := viper.BindPFlag(flagExportDir, fs.Lookup("dir")); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ExportCommand - save lists of selected kinds to directory, one file per list
+func ExportCommand(args []string) (exitCode int) {
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	return Export(ws, viper.GetString(flagExportDir), SelectedKinds())
+}
+
+// Export - save lists of given kinds to directory and write index file
+func Export(ws *c1ews.Client, dir string, kinds []*c1ews.ListKind) int {
+	index := &listfile.Index{}
+	for _, kind := range kinds {
+		lists, err := ws.ListLists(context.TODO(), kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCAPIError
+		}
+		kindIndex, err := listfile.WriteKind(dir, kind, lists)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCOther
+		}
+		log.Printf("%s: %d lists exported", kind.Name, len(lists))
+		index.Kinds = append(index.Kinds, *kindIndex)
+	}
+	if err := listfile.WriteIndex(dir, index); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	return 0
+}
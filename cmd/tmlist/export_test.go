@@ -0,0 +1,77 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  export_test.go - tests for export command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/listfile"
+	"github.com/spf13/viper"
+)
+
+func TestExport(t *testing.T) {
+	_, ws := newServer(t)
+	dir := t.TempDir()
+	if rc := Export(ws, dir, c1ews.ListKinds); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if _, err := os.Stat(filepath.Join(dir, listfile.IndexFileName)); err != nil {
+		t.Error(err)
+	}
+	files, err := listfile.ReadKind(dir, c1ews.DirectoryLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	expected := []string{"Database Servers", "SQL Server", "Windows"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("%v instead of %v", names, expected)
+	}
+	if !reflect.DeepEqual(files[0].Includes, []string{"Windows", "SQL Server"}) {
+		t.Errorf("wrong includes: %v", files[0].Includes)
+	}
+	first, err := os.ReadFile(filepath.Join(dir, "dir", "Windows.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc := Export(ws, dir, c1ews.ListKinds); rc != 0 {
+		t.Fatalf("second export return code %d", rc)
+	}
+	second, err := os.ReadFile(filepath.Join(dir, "dir", "Windows.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("export is not stable:\n%s\n%s", first, second)
+	}
+}
+
+func TestExportSelectedKinds(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	dir := t.TempDir()
+	viper.Set(flagExportDir, dir)
+	if rc := ExportCommand(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	for _, kind := range c1ews.ListKinds {
+		_, err := os.Stat(filepath.Join(dir, kind.ID))
+		if exported := err == nil; exported != kind.Exclusion {
+			t.Errorf("%s: exported is %v", kind.Name, exported)
+		}
+	}
+}
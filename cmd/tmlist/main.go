@@ -16,6 +16,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mpkondrashin/tmlist/pkg/c1ews"
 	"github.com/mpkondrashin/tmlist/pkg/process"
@@ -23,11 +24,31 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Version - TMList version. Set at build time using -ldflags "-X main.Version=..."
+var Version = "dev"
+
+const RCCommandLine = 2
+
 const (
 	RCOther = 3 + iota
 	RCAPIError
 	RCCycleDependence
 	RCListNotFound
+	RCInvalidItem
+	RCDrift
+	RCConflict
+	RCLimitExceeded
+	RCUnmarked
+	RCOutOfSync
+	RCDifferent
+	RCOwnedByOther
+	RCManualEdit
+)
+
+// Policies for lists with includes but without ownership marker
+const (
+	UnmarkedWarn   = "warn"
+	UnmarkedRefuse = "refuse"
 )
 
 const EnvPrefix = "TMLIST"
@@ -41,28 +62,193 @@ const (
 	flagAddress         = "address"
 	flagAPIKey          = "api_key"
 	flagIgnoreTLSErrors = "ignore_tls_errors"
-	flagDir             = "dir"
-	flagExt             = "ext"
-	flagFile            = "file"
+	flagCACert          = "ca_cert"
+	flagPinSHA256       = "pin_sha256"
+	flagClientCert      = "client_cert"
+	flagClientKey       = "client_key"
 	flagDryRun          = "dry"
+	flagDiff            = "diff"
+	flagOnConflict      = "on_conflict"
+	flagBackupDir       = "backup_dir"
+	flagMaxRemoved      = "max_removed_items"
+	flagMaxShrink       = "max_shrink_percent"
+	flagMaxModified     = "max_modified_lists"
+	flagForce           = "force"
+	flagInstance        = "instance"
+	flagUnmarked        = "unmarked"
+	flagOnManualEdit    = "on_manual_edit"
+	flagInboxList       = "inbox_list"
+	flagManifest        = "manifest"
+	flagManifestFile    = "manifest_file"
+	flagManifestMode    = "manifest_mode"
+	flagRecord          = "record"
+	flagReplay          = "replay"
+	flagTrace           = "trace"
+	flagTraceBodies     = "trace_bodies"
+	flagOffline         = "offline"
+	flagOfflineOutput   = "offline_output"
 )
 
-func Configure() {
-	fs := pflag.NewFlagSet("", pflag.ExitOnError)
+// Command - TMList subcommand
+type Command struct {
+	Name        string
+	Description string
+	// AllKinds - command handles all kinds of lists, so options
+	// to choose kinds are not available
+	AllKinds bool
+	// OwnProfiles - command runs other commands for selected profiles itself
+	OwnProfiles bool
+	// Flags - add command specific options
+	Flags func(fs *pflag.FlagSet)
+	// Run - execute command with given positional arguments. Return exit code
+	Run func(args []string) int
+}
+
+var commands = []*Command{
+	{
+		Name:        "run",
+		Description: "Populate lists with included lists content (default)",
+		Run:         RunCommand,
+	},
+	{
+		Name:        "plan",
+		Description: "Save changes to plan file without modifying lists",
+		Flags:       PlanFlags,
+		Run:         PlanCommand,
+	},
+	{
+		Name:        "apply",
+		Description: "Apply changes from plan file: tmlist apply <plan file>",
+		Run:         ApplyCommand,
+	},
+	{
+		Name:        "restore",
+		Description: "Restore lists from backup: tmlist restore --snapshot <file> [--list <name>]",
+		Flags:       RestoreFlags,
+		Run:         RestoreCommand,
+	},
+	{
+		Name:        "adopt",
+		Description: "Add ownership marker to lists: tmlist adopt --instance <name> --list <name>",
+		Flags:       AdoptFlags,
+		Run:         AdoptCommand,
+	},
+	{
+		Name:        "check",
+		Description: "Report lists out of sync without modifying them: tmlist check [--report <file>]",
+		Flags:       CheckFlags,
+		Run:         CheckCommand,
+	},
+	{
+		Name:        "export",
+		Description: "Save all lists to YAML files: tmlist export --dir <directory>",
+		AllKinds:    true,
+		Flags:       ExportFlags,
+		Run:         ExportCommand,
+	},
+	{
+		Name:        "sync",
+		Description: "Make lists match directory of list files: tmlist sync --from <directory> [--delete]",
+		Flags:       SyncFlags,
+		Run:         SyncCommand,
+	},
+	{
+		Name:        "replicate",
+		Description: "Copy lists between managers: tmlist replicate --source <profile> --target <profile> [--list <name>]",
+		Flags:       ReplicateFlags,
+		Run:         ReplicateCommand,
+	},
+	{
+		Name:        "compare",
+		Description: "Report differences between lists of two managers: tmlist compare --left <profile> --right <profile>",
+		AllKinds:    true,
+		Flags:       CompareFlags,
+		Run:         CompareCommand,
+	},
+	{
+		Name:        "serve",
+		Description: "Run lists processing periodically until stopped: tmlist serve --interval 15m",
+		OwnProfiles: true,
+		Flags:       ServeFlags,
+		Run:         ServeCommand,
+	},
+}
+
+// ParseCommand - return command chosen by first argument and remaining arguments
+func ParseCommand(args []string) (*Command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, nil
+	}
+	for _, command := range commands {
+		if command.Name == args[0] {
+			return command, args[1:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown command: %s", args[0])
+}
+
+func Usage(fs *pflag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [command] [options]\nCommands:\n", filepath.Base(os.Args[0]))
+		for _, command := range commands {
+			fmt.Fprintf(os.Stderr, "  %-8s %s\n", command.Name, command.Description)
+		}
+		fmt.Fprintf(os.Stderr, "Options:\n%s", fs.FlagUsages())
+	}
+}
+
+// Configure - parse command line options of given command and read configuration.
+// Return positional arguments
+func Configure(command *Command, args []string) []string {
+	fs := pflag.NewFlagSet(command.Name, pflag.ExitOnError)
+	fs.Usage = Usage(fs)
+	fs.String(flagProfile, "", "Use options from given section of profiles in configuration file")
+	fs.Bool(flagAllProfiles, false, "Run command for all profiles from configuration file")
 	fs.String(flagAddress, "", "Cloud One Woekload Security entry point URL")
 	fs.String(flagAPIKey, "", "Cloud One API Key")
 	fs.Bool(flagIgnoreTLSErrors, false, "Ignore all TLS errors")
-	fs.Bool(flagDir, false, "Process directory lists")
-	fs.Bool(flagExt, false, "Process file extension lists")
-	fs.Bool(flagFile, false, "Process file lists")
+	fs.String(flagCACert, "", "PEM file with CA certificates to verify server certificate")
+	fs.StringSlice(flagPinSHA256, nil, "Accept only server certificate with given SHA-256 fingerprint")
+	fs.String(flagClientCert, "", "PEM file with client certificate for mutual TLS")
+	fs.String(flagClientKey, "", "PEM file with client certificate private key")
+	if !command.AllKinds {
+		for _, kind := range c1ews.ListKinds {
+			fs.Bool(kind.ID, false, "Process "+strings.ToLower(kind.Name))
+		}
+	}
 	fs.Bool(flagDryRun, false, "Dyr run - do not modify existing lists")
-	err := fs.Parse(os.Args[1:])
+	fs.String(flagDiff, process.FormatText, "Changes output format for dry run and plan: text, color, markdown, json or none")
+	fs.String(flagOnConflict, ConflictRecompute, "Action if list was changed by somebody else during processing: recompute, skip or abort")
+	fs.String(flagBackupDir, "backup", "Directory to save lists content before modification (empty to disable)")
+	fs.Int(flagMaxRemoved, 0, "Abort if more items would be removed from one list (0 - no limit)")
+	fs.Int(flagMaxShrink, 0, "Abort if one list would shrink by more percents (0 - no limit)")
+	fs.Int(flagMaxModified, 0, "Abort if more lists would be modified (0 - no limit)")
+	fs.Bool(flagForce, false, "Modify lists even if safety limits are exceeded")
+	fs.String(flagInstance, "", "Modify only lists with \"Managed-By: tmlist/<instance>\" line in description")
+	fs.String(flagUnmarked, UnmarkedWarn, "Action for lists with includes but without ownership marker: warn or refuse")
+	fs.String(flagOnManualEdit, process.ManualEditWarn, "Action for generated lists edited in console: warn (do not modify), preserve (move manual items to inbox list) or overwrite")
+	fs.String(flagInboxList, "", "List to keep manually added items for preserve policy")
+	fs.String(flagManifestFile, "", "YAML file with lists composition rules (default - manifest section of configuration file)")
+	fs.String(flagManifestMode, process.ManifestMerge, "Use manifest rules together with \"Include:\" description lines (merge) or instead of them (replace)")
+	fs.String(flagRecord, "", "Record all API requests and responses to file")
+	fs.String(flagReplay, "", "Do not connect to server and replay API responses from file")
+	fs.String(flagOffline, "", "Do not connect to server; read lists from JSON snapshot file")
+	fs.String(flagOfflineOutput, "", "Save lists state after offline run to JSON file")
+	fs.Bool(flagTrace, false, "Log all API requests")
+	fs.Bool(flagTraceBodies, false, "Log also headers and bodies of API requests and responses (implies --trace)")
+	if command.Flags != nil {
+		command.Flags(fs)
+	}
+	err := fs.Parse(args)
 	if err != nil {
 		log.Fatal(err)
 	}
 	if err := viper.BindPFlags(fs); err != nil {
 		log.Fatal(err)
 	}
+	fs.Visit(func(f *pflag.Flag) {
+		commandLine[f.Name] = true
+	})
 	viper.SetEnvPrefix(EnvPrefix)
 	viper.AutomaticEnv()
 
@@ -81,84 +267,303 @@ func Configure() {
 			log.Fatal(err)
 		}
 	}
+	return fs.Args()
 }
 
-type (
-	List   func(context.Context) ([]c1ews.ListResponse, error)
-	Modify func(context.Context, int, *c1ews.List) (*c1ews.ListResponse, error)
-)
+// Compute - get lists of given kind and calculate their desired state
+func Compute(ws *c1ews.Client, kind *c1ews.ListKind) (*process.Process, int) {
+	r, err := ws.ListLists(context.TODO(), kind)
+	if err != nil {
+		log.Printf("%s: %v", kind.Name, err)
+		return nil, RCAPIError
+	}
+	return ProcessLists(kind, r)
+}
 
-func ProcessList(name string, list List, modify Modify, dryRun bool) int {
-	log.Printf("%s: Start", name)
-	r, err := list(context.TODO())
+// ProcessLists - calculate desired state of given lists
+func ProcessLists(kind *c1ews.ListKind, lists []c1ews.ListResponse) (*process.Process, int) {
+	p, err := NewKindProcess(kind, lists)
 	if err != nil {
 		log.Print(err)
-		return RCAPIError
+		return nil, SetupReturnCode(err)
+	}
+	if rc := RunProcess(kind, p); rc != 0 {
+		return nil, rc
 	}
-	p := process.NewProcess(r)
-	err = p.Process()
+	return p, 0
+}
+
+// RunProcess - compute lists of process created by NewKindProcess and
+// report problems found
+func RunProcess(kind *c1ews.ListKind, p *process.Process) int {
+	if err := p.Process(); err != nil {
+		log.Printf("%s: %v", kind.Name, err)
+		return ReturnCode(err)
+	}
+	ReportManualEdits(kind, p)
+	return CheckUnmarked(kind, p)
+}
+
+// NewKindProcess - create processing of given lists configured by options
+func NewKindProcess(kind *c1ews.ListKind, lists []c1ews.ListResponse) (*process.Process, error) {
+	p := process.NewProcess(lists).SetKind(kind).SetVersion(Version)
+	p.SetInstance(viper.GetString(flagInstance))
+	err := p.SetManualEditPolicy(viper.GetString(flagOnManualEdit), viper.GetString(flagInboxList))
 	if err != nil {
-		log.Printf("%s: %v", name, err)
-		if errors.Is(err, process.ErrListNotFound) {
-			return RCListNotFound
-		}
-		if errors.Is(err, process.ErrCycleDependence) {
-			return RCCycleDependence
+		return nil, fmt.Errorf("%s: %w", flagOnManualEdit, err)
+	}
+	manifest, err := LoadManifest()
+	if err != nil {
+		return nil, err
+	}
+	rules := manifest.ForKind(kind.ID)
+	if err := p.SetManifest(rules, viper.GetString(flagManifestMode)); err != nil {
+		return nil, fmt.Errorf("%s: %w", flagManifest, err)
+	}
+	if err := AddRemotes(p, kind, lists, rules); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadManifest - read lists composition rules from manifest file or
+// manifest section of configuration file
+func LoadManifest() (*process.Manifest, error) {
+	if path := viper.GetString(flagManifestFile); path != "" {
+		manifest, err := process.LoadManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", flagManifestFile, err)
 		}
-		return RCOther
-	}
-	count := 0
-	err = p.IterateChanged(func(list *c1ews.ListResponse) error {
-		count++
-		log.Printf("%s: modify %s", name, list.Name)
-		if dryRun {
-			return nil
+		return manifest, nil
+	}
+	var manifest process.Manifest
+	if err := viper.UnmarshalKey(flagManifest, &manifest.Rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", flagManifest, err)
+	}
+	return &manifest, nil
+}
+
+// ReportManualEdits - log generated lists that were edited in console
+func ReportManualEdits(kind *c1ews.ListKind, p *process.Process) {
+	for _, edit := range p.ManualEdits() {
+		switch viper.GetString(flagOnManualEdit) {
+		case process.ManualEditWarn:
+			log.Printf("%s: %s was edited manually, not modified until manual changes are reverted or %s is changed. Added items: %s. Removed items: %s",
+				kind.Name, edit.Name, flagOnManualEdit, strings.Join(edit.Items, ", "), strings.Join(edit.Removed, ", "))
+		case process.ManualEditPreserve:
+			log.Printf("%s: %s was edited manually. %d added items moved to %s, %d removed items will be added back",
+				kind.Name, edit.Name, len(edit.Items), viper.GetString(flagInboxList), len(edit.Removed))
+		default:
+			log.Printf("%s: %s was edited manually. %d added items will be removed, %d removed items will be added back",
+				kind.Name, edit.Name, len(edit.Items), len(edit.Removed))
 		}
-		l := process.ListFromResponse(list)
-		_, err := modify(context.TODO(), list.ID, l)
-		return err
-	})
-	if err != nil {
-		return RCAPIError
 	}
-	if count == 0 {
-		log.Printf("%s: No modifications", name)
+}
+
+// ManualEditCode - return error code if generated lists edited manually are
+// kept as is (on_manual_edit=warn). Such lists do not get changes of included
+// lists until manual changes are reverted or policy is changed
+func ManualEditCode(p *process.Process) int {
+	if len(p.ManualEdits()) > 0 && viper.GetString(flagOnManualEdit) == process.ManualEditWarn {
+		return RCManualEdit
+	}
+	return 0
+}
+
+// CheckUnmarked - report lists with includes but without ownership marker
+func CheckUnmarked(kind *c1ews.ListKind, p *process.Process) int {
+	unmarked := p.Unmarked()
+	for _, name := range unmarked {
+		log.Printf("%s: %s has includes but is not managed by %s. Use adopt command to manage it",
+			kind.Name, name, viper.GetString(flagInstance))
+	}
+	if len(unmarked) > 0 && viper.GetString(flagUnmarked) == UnmarkedRefuse {
+		return RCUnmarked
 	}
 	return 0
 }
 
-func main() {
-	Configure()
+// SetupReturnCode - return exit code corresponding to NewKindProcess error
+func SetupReturnCode(err error) int {
+	if errors.Is(err, ErrRemoteProfile) {
+		return RCAPIError
+	}
+	return RCCommandLine
+}
+
+// ReturnCode - return exit code corresponding to processing error
+func ReturnCode(err error) int {
+	if errors.Is(err, ErrRemoteProfile) {
+		return RCAPIError
+	}
+	if errors.Is(err, process.ErrListNotFound) {
+		return RCListNotFound
+	}
+	if errors.Is(err, process.ErrCycleDependence) {
+		return RCCycleDependence
+	}
+	if errors.Is(err, c1ews.ErrInvalidItem) {
+		return RCInvalidItem
+	}
+	return RCOther
+}
+
+// SelectedKinds - return list kinds chosen by command line options.
+// If none is chosen, return all kinds used for exclusions
+func SelectedKinds() (result []*c1ews.ListKind) {
+	for _, kind := range c1ews.ListKinds {
+		if viper.GetBool(kind.ID) {
+			result = append(result, kind)
+		}
+	}
+	if len(result) > 0 {
+		return
+	}
+	for _, kind := range c1ews.ListKinds {
+		if kind.Exclusion {
+			result = append(result, kind)
+		}
+	}
+	return
+}
+
+// NewClient - create API client using configuration. Error is returned
+// for missing or invalid parameters
+func NewClient() (*c1ews.Client, error) {
 	host := viper.GetString(flagAddress)
+	apikey := viper.GetString(flagAPIKey)
+	var tracer *c1ews.Tracer
+	if viper.GetBool(flagTrace) || viper.GetBool(flagTraceBodies) {
+		tracer = c1ews.NewTracer(viper.GetBool(flagTraceBodies))
+	}
+	if offline := viper.GetString(flagOffline); offline != "" {
+		ws, err := NewOfflineClient(offline)
+		if err != nil {
+			return nil, err
+		}
+		return ws.SetTracer(tracer), nil
+	}
+	replay := viper.GetString(flagReplay)
+	if replay != "" {
+		cassette, err := c1ews.LoadCassette(replay)
+		if err != nil {
+			return nil, err
+		}
+		return c1ews.NewWorkloadSecurity(apikey, host).SetReplayer(cassette).SetTracer(tracer), nil
+	}
 	if host == "" {
-		log.Fatal(fmt.Errorf("%s parameter is missing", flagAddress))
+		return nil, fmt.Errorf("%s parameter is missing", flagAddress)
 	}
-	apikey := viper.GetString(flagAPIKey)
 	if apikey == "" {
-		log.Fatal(fmt.Errorf("%s parameter is missing", flagAPIKey))
+		return nil, fmt.Errorf("%s parameter is missing", flagAPIKey)
 	}
 	ws := c1ews.NewWorkloadSecurity(apikey, host)
 	ws.SetIgnoreTLSErrors(viper.GetBool(flagIgnoreTLSErrors))
-	dryRun := viper.GetBool(flagDryRun)
-	all := !viper.GetBool(flagDir) && !viper.GetBool(flagExt) && !viper.GetBool(flagFile)
-	returnCode := 0
-	if viper.GetBool(flagDir) || all {
-		rc := ProcessList("Directory Lists", ws.ListDirectoryLists, ws.ModifyDirectoryList, dryRun)
-		if rc > returnCode {
-			returnCode = rc
+	ws.SetTracer(tracer)
+	if err := ConfigureTLS(ws); err != nil {
+		return nil, err
+	}
+	if viper.GetString(flagRecord) != "" {
+		ws.SetRecorder(c1ews.NewCassette())
+	}
+	return ws, nil
+}
+
+// ConfigureTLS - set CA certificates, pinned fingerprints and client certificate
+func ConfigureTLS(ws *c1ews.Client) error {
+	if caCert := viper.GetString(flagCACert); caCert != "" {
+		if err := ws.SetCACertFile(caCert); err != nil {
+			return fmt.Errorf("%s: %w", flagCACert, err)
 		}
 	}
-	if viper.GetBool(flagExt) || all {
-		rc := ProcessList("File Extension Lists", ws.ListFileExtensionLists, ws.ModifyFileExtensionList, dryRun)
-		if rc > returnCode {
-			returnCode = rc
+	if pins := viper.GetStringSlice(flagPinSHA256); len(pins) > 0 {
+		if err := ws.SetPinnedCertificates(pins...); err != nil {
+			return fmt.Errorf("%s: %w", flagPinSHA256, err)
 		}
 	}
-	if viper.GetBool(flagFile) || all {
-		rc := ProcessList("File Lists", ws.ListFileLists, ws.ModifyFileList, dryRun)
-		if rc > returnCode {
-			returnCode = rc
+	clientCert := viper.GetString(flagClientCert)
+	clientKey := viper.GetString(flagClientKey)
+	if clientCert == "" && clientKey == "" {
+		return nil
+	}
+	if clientCert == "" || clientKey == "" {
+		return fmt.Errorf("both %s and %s parameters should be provided", flagClientCert, flagClientKey)
+	}
+	if err := ws.SetClientCertificate(clientCert, clientKey); err != nil {
+		return fmt.Errorf("%s: %w", flagClientCert, err)
+	}
+	return nil
+}
+
+// SaveClient - write recorded API traffic of the client and its lists state
+// in offline mode to files if requested. Return exit code
+func SaveClient(ws *c1ews.Client) int {
+	returnCode := 0
+	if ws.Recorder != nil {
+		path := viper.GetString(flagRecord)
+		if err := ws.Recorder.Save(path); err != nil {
+			log.Print(err)
+			returnCode = RCOther
+		} else {
+			log.Printf("API traffic saved to %s", path)
+		}
+	}
+	return maxCode(returnCode, SaveOffline(ws))
+}
+
+// RunCommand - process all selected kinds of lists
+func RunCommand(args []string) int {
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	r := NewRunner(ws)
+	r.DryRun = viper.GetBool(flagDryRun)
+	r.BackupDir = viper.GetString(flagBackupDir)
+	ConfigureLimits(r)
+	if err := r.SetConflictPolicy(viper.GetString(flagOnConflict)); err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	returnCode := maxCode(r.Run(SelectedKinds()), SaveClient(ws))
+	if r.DryRun {
+		if err := WriteDiff(r.Diffs); err != nil {
+			log.Print(err)
+			return RCOther
 		}
 	}
-	os.Exit(returnCode)
+	return returnCode
+}
+
+// ConfigureLimits - set safety limits of runner from configuration
+func ConfigureLimits(r *Runner) {
+	r.Limits = process.Limits{
+		MaxRemovedItems:  viper.GetInt(flagMaxRemoved),
+		MaxShrinkPercent: viper.GetInt(flagMaxShrink),
+		MaxModifiedLists: viper.GetInt(flagMaxModified),
+	}
+	r.Force = viper.GetBool(flagForce)
+}
+
+// WriteDiff - output differences in format chosen by diff option
+func WriteDiff(diffs []process.ListDiff) error {
+	format := viper.GetString(flagDiff)
+	if format == "" || format == "none" {
+		return nil
+	}
+	return process.WriteDiff(os.Stdout, diffs, format)
+}
+
+func main() {
+	command, args, err := ParseCommand(os.Args[1:])
+	if err != nil {
+		log.Print(err)
+		os.Exit(RCCommandLine)
+	}
+	args = Configure(command, args)
+	if command.OwnProfiles {
+		os.Exit(command.Run(args))
+	}
+	os.Exit(RunProfiles(command, args))
 }
@@ -0,0 +1,191 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  main_test.go - end-to-end tests of TMList using fake API server
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews/c1ewstest"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/viper"
+)
+
+const fixture = "../../pkg/c1ews/c1ewstest/testdata/lists.yaml"
+
+func newServer(t *testing.T) (*c1ewstest.Server, *c1ews.Client) {
+	t.Helper()
+	s, err := c1ewstest.NewServerFromFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+}
+
+func TestProcessList(t *testing.T) {
+	s, ws := newServer(t)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected := []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("second run return code %d", rc)
+	}
+}
+
+func TestProcessListDryRun(t *testing.T) {
+	s, ws := newServer(t)
+	before := s.Lists(c1ews.DirectoryLists)
+	r := NewRunner(ws)
+	r.DryRun = true
+	if rc := r.ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists changed in dry run")
+	}
+	if len(r.Diffs) != 3 {
+		t.Errorf("%d diffs instead of 3", len(r.Diffs))
+	}
+}
+
+func TestProcessListErrors(t *testing.T) {
+	s, ws := newServer(t)
+	s.SetFaults(c1ewstest.Faults{InternalErrors: 1})
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != RCAPIError {
+		t.Errorf("return code %d instead of %d", rc, RCAPIError)
+	}
+	s.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:        "Broken",
+		Description: "Include: Missing",
+	})
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != RCListNotFound {
+		t.Errorf("return code %d instead of %d", rc, RCListNotFound)
+	}
+}
+
+// editOnDescribe - simulate console user adding item to the list when
+// TMList checks it for the first time
+func editOnDescribe(s *c1ewstest.Server, name, item string) {
+	edited := false
+	s.SetHook(func(r *http.Request) {
+		if edited || r.Method != http.MethodGet {
+			return
+		}
+		list := s.FindList(c1ews.DirectoryLists, name)
+		if r.URL.Path != fmt.Sprintf("/%s/%d", c1ews.DirectoryLists.Path, list.ID) {
+			return
+		}
+		edited = true
+		list.Items = append(list.Items, item)
+		s.SetList(c1ews.DirectoryLists, *list)
+	})
+}
+
+func TestProcessListConflict(t *testing.T) {
+	tests := []struct {
+		policy string
+		rc     int
+		items  []string
+	}{
+		{ConflictRecompute, 0, []string{`C:\Console`, `C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}},
+		{ConflictSkip, 0, []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}},
+		{ConflictAbort, RCConflict, []string{"manual item"}},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			s, ws := newServer(t)
+			editOnDescribe(s, "Windows", `C:\Console`)
+			r := NewRunner(ws)
+			if err := r.SetConflictPolicy(test.policy); err != nil {
+				t.Fatal(err)
+			}
+			if rc := r.ProcessList(c1ews.DirectoryLists); rc != test.rc {
+				t.Errorf("return code %d instead of %d", rc, test.rc)
+			}
+			actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+			if !reflect.DeepEqual(actual, test.items) {
+				t.Errorf("%v is not equal to %v", actual, test.items)
+			}
+			windows := s.FindList(c1ews.DirectoryLists, "Windows").Items
+			if windows[len(windows)-1] != `C:\Console` {
+				t.Errorf("console change is lost: %v", windows)
+			}
+		})
+	}
+}
+
+func TestProcessListLimits(t *testing.T) {
+	s, ws := newServer(t)
+	before := s.Lists(c1ews.DirectoryLists)
+	r := NewRunner(ws)
+	r.Limits.MaxModifiedLists = 2
+	if rc := r.ProcessList(c1ews.DirectoryLists); rc != RCLimitExceeded {
+		t.Errorf("return code %d instead of %d", rc, RCLimitExceeded)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists changed despite exceeded limit")
+	}
+	r = NewRunner(ws)
+	r.Limits.MaxModifiedLists = 2
+	r.Force = true
+	if rc := r.ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Errorf("forced run return code %d", rc)
+	}
+}
+
+func TestProcessListManifest(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(flagManifest, []map[string]any{{
+		"kind":    "dir",
+		"list":    "Database Servers",
+		"include": []string{"SQL Server"},
+		"items":   []string{`E:\Backup`},
+	}})
+	viper.Set(flagManifestMode, process.ManifestReplace)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected := []string{`D:\MSSQL\Data`, `E:\Backup`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+}
+
+func TestManualEditWarn(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	viper.Set(flagOnManualEdit, process.ManualEditWarn)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	db := s.FindList(c1ews.DirectoryLists, "Database Servers")
+	db.Items = append(db.Items, `E:\Manual`)
+	s.SetList(c1ews.DirectoryLists, *db)
+	windows := s.FindList(c1ews.DirectoryLists, "Windows")
+	windows.Items = append(windows.Items, `C:\New`)
+	s.SetList(c1ews.DirectoryLists, *windows)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != RCManualEdit {
+		t.Errorf("return code %d instead of %d", rc, RCManualEdit)
+	}
+	if actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items; !reflect.DeepEqual(actual, db.Items) {
+		t.Errorf("manually edited list was modified: %v", actual)
+	}
+}
@@ -0,0 +1,101 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  offline.go - operate on lists from snapshot file instead of server
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mpkondrashin/tmlist/internal/offline"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+// Address and API key of client in offline mode. Address is saved to plans
+// and backups instead of server address
+const (
+	offlineAddress = "http://offline"
+	offlineAPIKey  = "offline"
+)
+
+// LoadOffline - read lists from JSON snapshot file. Supported formats are
+// array of lists of one kind chosen by kind option, backup snapshot file,
+// and object with arrays of lists by kind ID (format of offline output)
+func LoadOffline(path string) (map[string][]c1ews.ListResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var lists []c1ews.ListResponse
+		if err := json.Unmarshal(data, &lists); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var kinds []*c1ews.ListKind
+		for _, kind := range c1ews.ListKinds {
+			if viper.GetBool(kind.ID) {
+				kinds = append(kinds, kind)
+			}
+		}
+		if len(kinds) != 1 {
+			return nil, fmt.Errorf("%s: exactly one list kind option is required for array of lists", path)
+		}
+		return map[string][]c1ews.ListResponse{kinds[0].ID: lists}, nil
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err == nil && snapshot.Kind != "" {
+		return map[string][]c1ews.ListResponse{snapshot.Kind: snapshot.Lists}, nil
+	}
+	var result map[string][]c1ews.ListResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
+
+// NewOfflineClient - return client answering requests from lists of
+// snapshot file kept in memory
+func NewOfflineClient(path string) (*c1ews.Client, error) {
+	lists, err := LoadOffline(path)
+	if err != nil {
+		return nil, err
+	}
+	store, err := offline.NewStore(lists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("Offline mode: lists are read from %s", path)
+	return c1ews.NewWorkloadSecurity(offlineAPIKey, offlineAddress).SetTransport(store), nil
+}
+
+// SaveOffline - write lists state of offline client to offline output file
+// if it is requested
+func SaveOffline(ws *c1ews.Client) int {
+	path := viper.GetString(flagOfflineOutput)
+	store, ok := ws.Transport.(*offline.Store)
+	if !ok || path == "" {
+		return 0
+	}
+	data, err := json.MarshalIndent(store.Snapshot(), "", "  ")
+	if err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	log.Printf("Offline mode: lists are saved to %s", path)
+	return 0
+}
@@ -0,0 +1,89 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  offline_test.go - tests for offline mode
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+const offlineLists = `[
+  {"ID": 1, "name": "Windows", "description": "", "items": ["C:\\Windows\\Temp"]},
+  {"ID": 2, "name": "SQL Server", "description": "", "items": ["D:\\MSSQL\\Data"]},
+  {"ID": 3, "name": "Database Servers", "description": "Include: Windows\nInclude: SQL Server", "items": []}
+]`
+
+func TestOffline(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "lists.json")
+	if err := os.WriteFile(input, []byte(offlineLists), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "result.json")
+	viper.Set(flagOffline, input)
+	viper.Set(flagOfflineOutput, output)
+	viper.Set(flagBackupDir, "")
+	viper.Set(flagOnConflict, ConflictRecompute)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := RunCommand(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string][]c1ews.ListResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	actual := result["dir"][2].Items
+	expected := []string{`C:\Windows\Temp`, `D:\MSSQL\Data`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	viper.Set(flagOffline, output)
+	viper.Set(flagOfflineOutput, "")
+	viper.Set(c1ews.DirectoryLists.ID, false)
+	viper.Set(flagReport, filepath.Join(dir, "report.json"))
+	if rc := CheckCommand(nil); rc != 0 {
+		t.Errorf("check of offline output return code %d", rc)
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	path := filepath.Join(t.TempDir(), "lists.json")
+	if err := os.WriteFile(path, []byte(offlineLists), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOffline(path); err == nil {
+		t.Errorf("array of lists is accepted without kind option")
+	}
+	path, err := SaveSnapshot(t.TempDir(), "", c1ews.FileExtensionLists, []c1ews.ListResponse{{Name: "Logs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists, err := LoadOffline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists["ext"]) != 1 {
+		t.Errorf("unexpected lists: %v", lists)
+	}
+}
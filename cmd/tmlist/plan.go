@@ -0,0 +1,269 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  plan.go - save changes to plan file and apply them later
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const flagOutput = "output"
+
+// Plan - changes computed by plan command
+type Plan struct {
+	Created time.Time   `json:"created"`
+	Address string      `json:"address"`
+	Kinds   []*KindPlan `json:"kinds"`
+}
+
+// KindPlan - changes of lists of one kind
+type KindPlan struct {
+	Kind string `json:"kind"`
+	// Observed - fingerprints of all lists of this kind at planning time by list ID
+	Observed map[int]string `json:"observed"`
+	Changes  []Change       `json:"changes"`
+}
+
+// Change - desired state of one list
+type Change struct {
+	ID      int        `json:"id"`
+	Desired c1ews.List `json:"desired"`
+}
+
+// Observe - return fingerprints of given lists by list ID
+func Observe(lists []c1ews.ListResponse) map[int]string {
+	result := make(map[int]string, len(lists))
+	for i := range lists {
+		result[lists[i].ID] = process.Fingerprint(&lists[i])
+	}
+	return result
+}
+
+// Drift - return names of lists which state differs from observed one
+func Drift(observed map[int]string, lists []c1ews.ListResponse) (result []string) {
+	current := Observe(lists)
+	for i := range lists {
+		fingerprint, ok := observed[lists[i].ID]
+		if !ok {
+			result = append(result, lists[i].Name+" (created)")
+			continue
+		}
+		if fingerprint != current[lists[i].ID] {
+			result = append(result, lists[i].Name)
+		}
+	}
+	for id := range observed {
+		if _, ok := current[id]; !ok {
+			result = append(result, fmt.Sprintf("ID %d (deleted)", id))
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+func PlanFlags(fs *pflag.FlagSet) {
+	fs.StringP(flagOutput, "o", "plan.json", "Plan file name")
+}
+
+// PlanCommand - compute changes for selected kinds of lists and save them to file
+func PlanCommand(args []string) int {
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	plan := &Plan{
+		Created: time.Now().UTC(),
+		Address: ws.Host,
+	}
+	returnCode := 0
+	count := 0
+	var diffs []process.ListDiff
+	for _, kind := range SelectedKinds() {
+		p, rc := Compute(ws, kind)
+		if rc != 0 {
+			if rc > returnCode {
+				returnCode = rc
+			}
+			continue
+		}
+		diffs = append(diffs, p.Diff()...)
+		kindPlan := &KindPlan{
+			Kind:     kind.ID,
+			Observed: Observe(p.Observed()),
+		}
+		_ = p.IterateChanged(func(list *c1ews.ListResponse) error {
+			log.Printf("%s: plan to modify %s", kind.Name, list.Name)
+			kindPlan.Changes = append(kindPlan.Changes, Change{
+				ID:      list.ID,
+				Desired: *process.ListFromResponse(list),
+			})
+			return nil
+		})
+		count += len(kindPlan.Changes)
+		plan.Kinds = append(plan.Kinds, kindPlan)
+	}
+	returnCode = maxCode(returnCode, SaveClient(ws))
+	if returnCode != 0 {
+		return returnCode
+	}
+	path := viper.GetString(flagOutput)
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	log.Printf("%d changes saved to %s", count, path)
+	if err := WriteDiff(diffs); err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	return 0
+}
+
+// LoadPlan - read plan from file
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// ApplyCommand - apply changes from plan file if lists did not change since planning
+func ApplyCommand(args []string) (exitCode int) {
+	if len(args) != 1 {
+		log.Print("apply: plan file name expected")
+		return RCCommandLine
+	}
+	path := args[0]
+	if currentProfile != "" && viper.GetBool(flagAllProfiles) {
+		// Plan of each profile is saved to its own file
+		path = ProfilePath(path, currentProfile, false)
+	}
+	plan, err := LoadPlan(path)
+	if err != nil {
+		log.Print(err)
+		return RCOther
+	}
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	if plan.Address != ws.Host {
+		log.Printf("plan was created for %s and can not be applied to %s", plan.Address, ws.Host)
+		return RCDrift
+	}
+	kinds := make([]*c1ews.ListKind, len(plan.Kinds))
+	for i, kindPlan := range plan.Kinds {
+		kinds[i] = c1ews.FindListKind(kindPlan.Kind)
+		if kinds[i] == nil {
+			log.Printf("%s: unknown list kind", kindPlan.Kind)
+			return RCOther
+		}
+	}
+	drift := false
+	current := make([][]c1ews.ListResponse, len(plan.Kinds))
+	for i, kindPlan := range plan.Kinds {
+		lists, err := ws.ListLists(context.TODO(), kinds[i])
+		if err != nil {
+			log.Printf("%s: %v", kinds[i].Name, err)
+			return RCAPIError
+		}
+		current[i] = lists
+		for _, name := range Drift(kindPlan.Observed, lists) {
+			log.Printf("%s: %s changed since planning", kinds[i].Name, name)
+			drift = true
+		}
+	}
+	if drift {
+		log.Print("Plan is outdated. Run plan command again")
+		return RCDrift
+	}
+	if rc := CheckPlanLimits(plan, current); rc != 0 {
+		return rc
+	}
+	dryRun := viper.GetBool(flagDryRun)
+	for i, kindPlan := range plan.Kinds {
+		var changed []c1ews.ListResponse
+		for _, change := range kindPlan.Changes {
+			for _, l := range current[i] {
+				if l.ID == change.ID {
+					changed = append(changed, l)
+				}
+			}
+		}
+		if !dryRun {
+			if rc := Backup(viper.GetString(flagBackupDir), ws.Host, kinds[i], changed); rc != 0 {
+				return rc
+			}
+		}
+		for _, change := range kindPlan.Changes {
+			log.Printf("%s: modify %s", kinds[i].Name, change.Desired.Name)
+			if dryRun {
+				continue
+			}
+			desired := change.Desired
+			if _, err := ws.ModifyList(context.TODO(), kinds[i], change.ID, &desired); err != nil {
+				log.Printf("%s: %v", kinds[i].Name, err)
+				return RCAPIError
+			}
+		}
+	}
+	return 0
+}
+
+// CheckPlanLimits - check changes from plan against safety limits
+func CheckPlanLimits(plan *Plan, current [][]c1ews.ListResponse) int {
+	var diffs []process.ListDiff
+	for i, kindPlan := range plan.Kinds {
+		for _, change := range kindPlan.Changes {
+			for j := range current[i] {
+				if current[i][j].ID != change.ID {
+					continue
+				}
+				desired := c1ews.ListResponse{
+					ID:          change.ID,
+					Name:        change.Desired.Name,
+					Description: change.Desired.Description,
+					Items:       change.Desired.Items,
+				}
+				d := process.Diff(&current[i][j], &desired)
+				d.Kind = kindPlan.Kind
+				diffs = append(diffs, d)
+			}
+		}
+	}
+	r := &Runner{Diffs: diffs}
+	ConfigureLimits(r)
+	return r.checkLimits()
+}
@@ -0,0 +1,90 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  plan_test.go - tests for plan and apply commands
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func configureServer(t *testing.T, url, apiKey string) {
+	t.Helper()
+	viper.Reset()
+	viper.Set(flagAddress, url)
+	viper.Set(flagAPIKey, apiKey)
+	t.Cleanup(viper.Reset)
+}
+
+func TestPlanApply(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	viper.Set(flagOutput, path)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	before := s.Lists(c1ews.DirectoryLists)
+	if rc := PlanCommand(nil); rc != 0 {
+		t.Fatalf("plan return code %d", rc)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Fatalf("plan modified lists")
+	}
+	if rc := ApplyCommand([]string{path}); rc != 0 {
+		t.Fatalf("apply return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected := []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+}
+
+func TestApplyDrift(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	viper.Set(flagOutput, path)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := PlanCommand(nil); rc != 0 {
+		t.Fatalf("plan return code %d", rc)
+	}
+	list := s.FindList(c1ews.DirectoryLists, "Windows")
+	list.Items = append(list.Items, `C:\New`)
+	s.SetList(c1ews.DirectoryLists, *list)
+	before := s.Lists(c1ews.DirectoryLists)
+	if rc := ApplyCommand([]string{path}); rc != RCDrift {
+		t.Errorf("apply return code %d instead of %d", rc, RCDrift)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("outdated plan was applied")
+	}
+}
+
+func TestApplyDryRun(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	viper.Set(flagOutput, path)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := PlanCommand(nil); rc != 0 {
+		t.Fatalf("plan return code %d", rc)
+	}
+	before := s.Lists(c1ews.DirectoryLists)
+	viper.Set(flagDryRun, true)
+	if rc := ApplyCommand([]string{path}); rc != 0 {
+		t.Errorf("apply return code %d", rc)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists changed in dry run")
+	}
+}
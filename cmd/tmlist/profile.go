@@ -0,0 +1,188 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  profile.go - run commands for several managers from one configuration
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	flagProfile     = "profile"
+	flagAllProfiles = "all_profiles"
+	profilesKey     = "profiles"
+)
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// commandLine - options provided on command line. They override profile options
+var commandLine = make(map[string]bool)
+
+// profileBase - values of options before they were overridden by current profile
+var profileBase = make(map[string]any)
+
+// currentProfile - name of applied profile or empty string
+var currentProfile string
+
+// profileOutputs - options with names of files written by commands (true
+// for directories) and files of offline and replay modes. Profile name is
+// added to them if several profiles are used in one run, so profiles do not
+// overwrite files of each other and do not read lists of other manager
+var profileOutputs = map[string]bool{
+	flagOutput:        false,
+	flagReport:        false,
+	flagExportDir:     true,
+	flagBackupDir:     true,
+	flagRecord:        false,
+	flagReplay:        false,
+	flagOffline:       false,
+	flagOfflineOutput: false,
+}
+
+// Profiles - return names of all profiles from configuration in sorted order
+func Profiles() []string {
+	names := maps.Keys(viper.GetStringMap(profilesKey))
+	sort.Strings(names)
+	return names
+}
+
+// SelectedProfiles - return profiles chosen by profile and all_profiles options.
+// Empty result means options outside of profiles are used
+func SelectedProfiles() ([]string, error) {
+	if viper.GetBool(flagAllProfiles) {
+		names := Profiles()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s: no profiles in configuration", flagAllProfiles)
+		}
+		return names, nil
+	}
+	if name := viper.GetString(flagProfile); name != "" {
+		return []string{name}, nil
+	}
+	return nil, nil
+}
+
+// ApplyProfile - override options with values from given profile. Options
+// provided on command line or by environment variables are kept intact.
+// Options overridden by previously applied profile are restored first
+func ApplyProfile(name string) error {
+	return applyProfile(name, false)
+}
+
+// applyProfile - override options with values from given profile. If strict
+// is true, profile values override also command line and environment
+func applyProfile(name string, strict bool) error {
+	restoreBase()
+	key := profilesKey + "." + name
+	if !viper.IsSet(key) {
+		return fmt.Errorf("%s: %w", name, ErrUnknownProfile)
+	}
+	for option, value := range viper.GetStringMap(key) {
+		if !strict && commandLine[option] {
+			continue
+		}
+		if _, found := os.LookupEnv(EnvPrefix + "_" + strings.ToUpper(option)); found && !strict {
+			continue
+		}
+		profileBase[option] = viper.Get(option)
+		viper.Set(option, value)
+	}
+	if strict || viper.GetBool(flagAllProfiles) {
+		values := viper.GetStringMap(key)
+		for option, dir := range profileOutputs {
+			if _, found := values[option]; found {
+				continue
+			}
+			path := viper.GetString(option)
+			if path == "" {
+				continue
+			}
+			if _, found := profileBase[option]; !found {
+				profileBase[option] = viper.Get(option)
+			}
+			viper.Set(option, ProfilePath(path, name, dir))
+		}
+	}
+	currentProfile = name
+	return nil
+}
+
+// ProfilePath - return file name with profile name added before extension
+// (plan.json -> plan-prod.json) or subdirectory of directory named by profile
+func ProfilePath(path, profile string, dir bool) string {
+	if dir {
+		return filepath.Join(path, profile)
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + profile + ext
+}
+
+// restoreBase - restore options overridden by current profile
+func restoreBase() {
+	for key, value := range profileBase {
+		viper.Set(key, value)
+	}
+	profileBase = make(map[string]any)
+	currentProfile = ""
+}
+
+// WithProfile - call function with options of given profile applied. Profile
+// options override command line and environment, so other manager can be
+// accessed. Options of current profile are restored afterwards
+func WithProfile(name string, f func() error) error {
+	previous := currentProfile
+	if err := applyProfile(name, true); err != nil {
+		return err
+	}
+	err := f()
+	restoreBase()
+	if previous != "" {
+		if err := ApplyProfile(previous); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// RunProfiles - run command for each selected profile or once if no profiles
+// are selected. Return highest exit code
+func RunProfiles(command *Command, args []string) int {
+	names, err := SelectedProfiles()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	if len(names) == 0 {
+		return command.Run(args)
+	}
+	returnCode := 0
+	for _, name := range names {
+		if err := ApplyProfile(name); err != nil {
+			log.Print(err)
+			returnCode = maxCode(returnCode, RCCommandLine)
+			continue
+		}
+		log.Printf("Profile %s: Start", name)
+		rc := command.Run(args)
+		if rc != 0 {
+			log.Printf("Profile %s: return code %d", name, rc)
+		}
+		returnCode = maxCode(returnCode, rc)
+	}
+	return returnCode
+}
@@ -0,0 +1,225 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  profile_test.go - tests for profiles
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func resetProfiles(t *testing.T) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		profileBase = make(map[string]any)
+		currentProfile = ""
+	})
+}
+
+func TestApplyProfile(t *testing.T) {
+	resetProfiles(t)
+	viper.Set(flagAddress, "https://default")
+	viper.Set(flagMaxRemoved, 5)
+	viper.Set(profilesKey, map[string]any{
+		"prod": map[string]any{flagAddress: "https://prod", flagMaxRemoved: 10, "dir": true},
+		"lab":  map[string]any{flagAddress: "https://lab"},
+	})
+	commandLine[flagMaxRemoved] = true
+	t.Cleanup(func() { delete(commandLine, flagMaxRemoved) })
+	if err := ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if viper.GetString(flagAddress) != "https://prod" || !viper.GetBool("dir") {
+		t.Errorf("profile options are not applied")
+	}
+	if viper.GetInt(flagMaxRemoved) != 5 {
+		t.Errorf("command line option is overridden by profile")
+	}
+	if err := ApplyProfile("lab"); err != nil {
+		t.Fatal(err)
+	}
+	if viper.GetString(flagAddress) != "https://lab" || viper.GetBool("dir") {
+		t.Errorf("options of previous profile are not restored")
+	}
+	if err := ApplyProfile("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("error %v instead of %v", err, ErrUnknownProfile)
+	}
+}
+
+func TestRunProfiles(t *testing.T) {
+	s1, _ := newServer(t)
+	s2, _ := newServer(t)
+	resetProfiles(t)
+	viper.Set(profilesKey, map[string]any{
+		"first":  map[string]any{flagAddress: s1.URL, flagAPIKey: s1.APIKey, "dir": true},
+		"second": map[string]any{flagAddress: s2.URL, flagAPIKey: "wrong:key"},
+	})
+	viper.Set(flagAllProfiles, true)
+	viper.Set(flagReport, filepath.Join(t.TempDir(), "report.json"))
+	command := &Command{Name: "check", Run: CheckCommand}
+	if rc := RunProfiles(command, nil); rc != RCOutOfSync {
+		t.Errorf("return code %d instead of %d", rc, RCOutOfSync)
+	}
+	if s2.Requests() == 0 {
+		t.Errorf("second profile was not processed")
+	}
+	viper.Set(flagAllProfiles, false)
+	viper.Set(flagProfile, "second")
+	if rc := RunProfiles(command, nil); rc != RCAPIError {
+		t.Errorf("return code %d instead of %d", rc, RCAPIError)
+	}
+}
+
+func TestRemoteInclude(t *testing.T) {
+	golden, _ := newServer(t)
+	tenant, ws := newServer(t)
+	resetProfiles(t)
+	viper.Set(flagAddress, tenant.URL)
+	viper.Set(flagAPIKey, tenant.APIKey)
+	viper.Set(flagOnConflict, ConflictRecompute)
+	record := filepath.Join(t.TempDir(), "record.json")
+	viper.Set(flagRecord, record)
+	viper.Set(profilesKey, map[string]any{
+		"golden": map[string]any{flagAddress: golden.URL, flagAPIKey: golden.APIKey},
+	})
+	commandLine[flagAddress] = true
+	t.Cleanup(func() { delete(commandLine, flagAddress) })
+	golden.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:  "Standard",
+		Items: []string{`C:\Golden`},
+	})
+	tenant.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:        "Tenant",
+		Description: "Include: golden/Standard\nInclude: Windows",
+	})
+	goldenBefore := golden.Lists(c1ews.DirectoryLists)
+	if rc := NewRunner(ws).ProcessList(c1ews.DirectoryLists); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	actual := tenant.FindList(c1ews.DirectoryLists, "Tenant").Items
+	expected := []string{`C:\Golden`, `C:\Windows\Temp`, `C:\pagefile.sys`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	if !reflect.DeepEqual(goldenBefore, golden.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("remote lists are modified")
+	}
+	if viper.GetString(flagAddress) != tenant.URL || viper.GetString(flagRecord) != record {
+		t.Errorf("options are not restored after remote profile")
+	}
+	cassette, err := c1ews.LoadCassette(ProfilePath(record, "golden", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws = c1ews.NewWorkloadSecurity(golden.APIKey, golden.URL).SetReplayer(cassette)
+	if _, err := ws.ListDirectoryLists(context.Background()); err != nil {
+		t.Errorf("remote traffic is not recorded to its own file: %v", err)
+	}
+}
+
+func TestProfileOutputs(t *testing.T) {
+	s1, _ := newServer(t)
+	s2, _ := newServer(t)
+	resetProfiles(t)
+	viper.Set(profilesKey, map[string]any{
+		"first":  map[string]any{flagAddress: s1.URL, flagAPIKey: s1.APIKey},
+		"second": map[string]any{flagAddress: s2.URL, flagAPIKey: s2.APIKey},
+	})
+	dir := t.TempDir()
+	viper.Set(flagAllProfiles, true)
+	viper.Set(flagBackupDir, "")
+	viper.Set(flagOutput, filepath.Join(dir, "plan.json"))
+	viper.Set(flagExportDir, filepath.Join(dir, "lists"))
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := RunProfiles(&Command{Name: "plan", Run: PlanCommand}, nil); rc != 0 {
+		t.Fatalf("plan return code %d", rc)
+	}
+	if rc := RunProfiles(&Command{Name: "export", Run: ExportCommand}, nil); rc != 0 {
+		t.Fatalf("export return code %d", rc)
+	}
+	for _, name := range []string{"first", "second"} {
+		if _, err := LoadPlan(filepath.Join(dir, "plan-"+name+".json")); err != nil {
+			t.Error(err)
+		}
+		files, err := os.ReadDir(filepath.Join(dir, "lists", name))
+		if err != nil || len(files) == 0 {
+			t.Errorf("%s: lists are not exported: %v", name, err)
+		}
+	}
+	viper.Set(flagBackupDir, filepath.Join(dir, "backup"))
+	apply := &Command{Name: "apply", Run: ApplyCommand}
+	if rc := RunProfiles(apply, []string{filepath.Join(dir, "plan.json")}); rc != 0 {
+		t.Errorf("apply return code %d", rc)
+	}
+	for _, name := range []string{"first", "second"} {
+		files, err := filepath.Glob(filepath.Join(dir, "backup", name, "*.json"))
+		if err != nil || len(files) == 0 {
+			t.Errorf("%s: lists are not backed up to own directory: %v", name, err)
+		}
+	}
+	if actual := ProfilePath("report", "prod", false); actual != "report-prod" {
+		t.Errorf("%s is not equal to report-prod", actual)
+	}
+}
+
+func TestRemoteIncludeOffline(t *testing.T) {
+	resetProfiles(t)
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.json")
+	if err := os.WriteFile(local, []byte(`{"dir": [
+  {"ID": 1, "name": "Windows", "description": "", "items": ["C:\\Windows\\Temp"]},
+  {"ID": 2, "name": "Tenant", "description": "Include: golden/Standard\nInclude: Windows", "items": []}
+]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join(dir, "golden.json")
+	if err := os.WriteFile(golden, []byte(`{"dir": [
+  {"ID": 1, "name": "Standard", "description": "", "items": ["C:\\Golden"]}
+]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.json")
+	viper.Set(flagOffline, local)
+	viper.Set(flagOfflineOutput, output)
+	viper.Set(flagBackupDir, "")
+	viper.Set(flagOnConflict, ConflictRecompute)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	viper.Set(profilesKey, map[string]any{
+		"golden": map[string]any{flagOffline: golden},
+	})
+	if rc := RunCommand(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	lists, err := LoadOffline(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, l := range lists["dir"] {
+		if l.Name == "Tenant" {
+			actual = l.Items
+		}
+	}
+	expected := []string{`C:\Golden`, `C:\Windows\Temp`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	if viper.GetString(flagOffline) != local || viper.GetString(flagOfflineOutput) != output {
+		t.Errorf("options are not restored after remote profile")
+	}
+}
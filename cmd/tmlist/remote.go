@@ -0,0 +1,70 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  remote.go - fetch lists of profiles referred by qualified includes
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+)
+
+var ErrRemoteProfile = errors.New("remote profile")
+
+// AddRemotes - fetch lists of given kind from profiles referred by
+// "<profile>/<list name>" includes of given lists, manifest rules and
+// fetched lists, and add them to the process
+func AddRemotes(p *process.Process, kind *c1ews.ListKind, lists []c1ews.ListResponse, rules []process.Rule) error {
+	profiles := make(map[string]bool)
+	for _, name := range Profiles() {
+		profiles[name] = true
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	var includes []string
+	for i := range lists {
+		includes = append(includes, process.Includes(&lists[i])...)
+	}
+	for _, rule := range rules {
+		includes = append(includes, rule.Include...)
+		includes = append(includes, rule.Exclude...)
+	}
+	fetched := make(map[string]bool)
+	for len(includes) > 0 {
+		remote, _, ok := process.SplitRemote(includes[0])
+		includes = includes[1:]
+		if !ok || fetched[remote] || !profiles[strings.ToLower(remote)] {
+			continue
+		}
+		fetched[remote] = true
+		var remoteLists []c1ews.ListResponse
+		err := WithProfile(remote, func() (err error) {
+			ws, err := NewClient()
+			if err != nil {
+				return err
+			}
+			defer SaveClient(ws)
+			remoteLists, err = ws.ListLists(context.TODO(), kind)
+			return
+		})
+		if err != nil {
+			return fmt.Errorf("%w %s: %v", ErrRemoteProfile, remote, err)
+		}
+		p.SetRemote(remote, remoteLists)
+		for i := range remoteLists {
+			includes = append(includes, process.Includes(&remoteLists[i])...)
+		}
+	}
+	return nil
+}
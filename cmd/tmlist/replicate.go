@@ -0,0 +1,214 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  replicate.go - copy lists from one manager to others
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagSource = "source"
+	flagTarget = "target"
+)
+
+func ReplicateFlags(fs *pflag.FlagSet) {
+	fs.String(flagSource, "", "Profile of manager to copy lists from")
+	fs.StringSlice(flagTarget, nil, "Profile of manager to copy lists to (can be repeated)")
+	fs.StringSlice(flagList, nil, "Name of the list to copy (can be repeated, default - all lists)")
+}
+
+// ReplicateCommand - copy lists of selected kinds from source profile
+// manager to target profiles managers. Kinds and lists are chosen by
+// options outside of profiles
+func ReplicateCommand(args []string) int {
+	source := viper.GetString(flagSource)
+	targets := viper.GetStringSlice(flagTarget)
+	if source == "" || len(targets) == 0 {
+		log.Printf("both %s and %s parameters should be provided", flagSource, flagTarget)
+		return RCCommandLine
+	}
+	names := viper.GetStringSlice(flagList)
+	kinds := SelectedKinds()
+	lists := make([][]c1ews.ListResponse, len(kinds))
+	found := make(map[string]bool)
+	err := WithProfile(source, func() error {
+		ws, err := NewClient()
+		if err != nil {
+			return err
+		}
+		defer SaveClient(ws)
+		for i, kind := range kinds {
+			all, err := ws.ListLists(context.TODO(), kind)
+			if err != nil {
+				return fmt.Errorf("%s: %w", kind.Name, err)
+			}
+			lists[i] = SelectLists(all, names)
+			for _, l := range lists[i] {
+				found[l.Name] = true
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrUnknownProfile) {
+		log.Print(err)
+		return RCCommandLine
+	}
+	if err != nil {
+		log.Printf("%s: %v", source, err)
+		return RCAPIError
+	}
+	for _, name := range names {
+		if !found[name] {
+			log.Printf("%s: %s: %v", source, name, process.ErrListNotFound)
+			return RCListNotFound
+		}
+	}
+	returnCode := 0
+	for _, target := range targets {
+		rc := 0
+		err := WithProfile(target, func() error {
+			log.Printf("Replicate from %s to %s", source, target)
+			ws, err := NewClient()
+			if err != nil {
+				return err
+			}
+			rc = ReplicateTo(ws, kinds, lists)
+			return nil
+		})
+		if err != nil {
+			log.Print(err)
+			rc = RCCommandLine
+		}
+		returnCode = maxCode(returnCode, rc)
+	}
+	return returnCode
+}
+
+// ReplicateTo - copy given lists to manager. Lists are matched by name.
+// Missing lists are created; existing lists keep their IDs
+func ReplicateTo(ws *c1ews.Client, kinds []*c1ews.ListKind, lists [][]c1ews.ListResponse) (exitCode int) {
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	var plans []*SyncPlan
+	var diffs []process.ListDiff
+	for i, kind := range kinds {
+		current, err := ws.ListLists(context.TODO(), kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCAPIError
+		}
+		plan := ComputeReplication(kind, lists[i], current)
+		plans = append(plans, plan)
+		diffs = append(diffs, plan.Diffs...)
+	}
+	r := NewRunner(ws)
+	ConfigureLimits(r)
+	r.Diffs = diffs
+	if rc := r.checkLimits(); rc != 0 {
+		return rc
+	}
+	if viper.GetBool(flagDryRun) {
+		for _, plan := range plans {
+			LogSyncPlan(plan)
+		}
+		if err := WriteDiff(diffs); err != nil {
+			log.Print(err)
+			return RCOther
+		}
+		return 0
+	}
+	for _, plan := range plans {
+		if rc := ApplySync(ws, viper.GetString(flagBackupDir), plan); rc != 0 {
+			return rc
+		}
+	}
+	return 0
+}
+
+// ComputeReplication - return changes required to make target lists
+// equal to source lists with the same names
+func ComputeReplication(kind *c1ews.ListKind, source, target []c1ews.ListResponse) *SyncPlan {
+	existing := make(map[string]c1ews.ListResponse)
+	for _, l := range target {
+		existing[l.Name] = l
+	}
+	plan := &SyncPlan{Kind: kind}
+	for _, l := range source {
+		before, ok := existing[l.Name]
+		if !ok {
+			desired := Replica(&l, nil)
+			plan.Create = append(plan.Create, desired)
+			plan.Diffs = append(plan.Diffs, kindDiff(kind, &c1ews.ListResponse{}, &desired))
+			continue
+		}
+		desired := Replica(&l, &before)
+		if sameList(&before, &desired) {
+			continue
+		}
+		plan.Modify = append(plan.Modify, desired)
+		plan.Before = append(plan.Before, before)
+		plan.Diffs = append(plan.Diffs, kindDiff(kind, &before, &desired))
+	}
+	return plan
+}
+
+// Replica - return copy of source list to be written to target manager
+// instead of target list (nil if there is no such list). Metadata of source
+// instance (owner, dependences, items hash and sync metadata) is replaced by
+// metadata of target list, so target instance keeps managing its lists
+func Replica(source, target *c1ews.ListResponse) c1ews.ListResponse {
+	result := *source
+	result.ID = 0
+	process.ClearDependence(&result)
+	process.ClearSyncMetadata(&result)
+	process.ClearValues(&result, process.ManagedByKey)
+	process.ClearValues(&result, process.HashKey)
+	if target == nil {
+		return result
+	}
+	result.ID = target.ID
+	if owner := process.ManagedBy(target); owner != "" {
+		process.SetManagedBy(&result, owner)
+	}
+	if process.StoredHash(target) != "" {
+		// Replicated items are written by TMList, so they are not reported
+		// as manual edit by target instance
+		process.SetItemsHash(&result)
+	}
+	process.AddDependences(&result, process.ListDependencies(target)...)
+	return result
+}
+
+// SelectLists - return lists with given names. All lists are returned if
+// no names are given
+func SelectLists(lists []c1ews.ListResponse, names []string) []c1ews.ListResponse {
+	if len(names) == 0 {
+		return lists
+	}
+	var result []c1ews.ListResponse
+	for _, l := range lists {
+		for _, name := range names {
+			if l.Name == name {
+				result = append(result, l)
+				break
+			}
+		}
+	}
+	return result
+}
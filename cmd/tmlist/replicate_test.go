@@ -0,0 +1,102 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  replicate_test.go - tests for replicate command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews/c1ewstest"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/viper"
+)
+
+func TestReplicate(t *testing.T) {
+	source, _ := newServer(t)
+	target, err := c1ewstest.NewServer(&c1ewstest.Fixture{
+		APIKey: "target:secret",
+		Lists: map[string][]c1ews.ListResponse{
+			"dir": {{ID: 100, Name: "Windows", Items: []string{`C:\Old`}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(target.Close)
+	resetProfiles(t)
+	viper.Set(profilesKey, map[string]any{
+		"golden":   map[string]any{flagAddress: source.URL, flagAPIKey: source.APIKey},
+		"customer": map[string]any{flagAddress: target.URL, flagAPIKey: target.APIKey},
+	})
+	viper.Set(flagSource, "golden")
+	viper.Set(flagTarget, []string{"customer"})
+	viper.Set(flagList, []string{"Windows", "SQL Server"})
+	viper.Set(flagBackupDir, "")
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := ReplicateCommand(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	windows := target.FindList(c1ews.DirectoryLists, "Windows")
+	if windows.ID != 100 {
+		t.Errorf("target list ID changed to %d", windows.ID)
+	}
+	expected := source.FindList(c1ews.DirectoryLists, "Windows").Items
+	if !reflect.DeepEqual(windows.Items, expected) {
+		t.Errorf("%v is not equal to %v", windows.Items, expected)
+	}
+	if target.FindList(c1ews.DirectoryLists, "SQL Server") == nil {
+		t.Errorf("missing list is not created")
+	}
+	if target.FindList(c1ews.DirectoryLists, "Database Servers") != nil {
+		t.Errorf("not selected list is replicated")
+	}
+	viper.Set(flagList, []string{"Missing"})
+	if rc := ReplicateCommand(nil); rc != RCListNotFound {
+		t.Errorf("return code %d instead of %d", rc, RCListNotFound)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	source := &c1ews.ListResponse{
+		ID:   1,
+		Name: "Windows",
+		Description: "Windows folders\nManaged-By: tmlist/golden\n" +
+			process.DependencePrefix + " Servers\nTMList-Hash: 0123456789abcdef\n" +
+			"TMList-Synced: 2023-01-01T00:00:00Z\nTMList-Version: 1\nTMList-Count: 1\nTMList-Sources: golden.txt",
+		Items: []string{`C:\Windows\Temp`},
+	}
+	created := Replica(source, nil)
+	if created.ID != 0 || created.Description != "Windows folders" {
+		t.Errorf("unexpected new replica: %+v", created)
+	}
+	target := &c1ews.ListResponse{
+		ID:          100,
+		Name:        "Windows",
+		Description: "Old\nManaged-By: tmlist/customer\nTMList-Hash: fedcba9876543210\n" + process.DependencePrefix + " Hosts",
+		Items:       []string{`C:\Old`},
+	}
+	replica := Replica(source, target)
+	if replica.ID != 100 {
+		t.Errorf("ID %d instead of 100", replica.ID)
+	}
+	if owner := process.ManagedBy(&replica); owner != "customer" {
+		t.Errorf("owner %s instead of customer", owner)
+	}
+	if process.ManuallyEdited(&replica) {
+		t.Errorf("replica is reported as manually edited")
+	}
+	if deps := process.ListDependencies(&replica); !reflect.DeepEqual(deps, []string{"Hosts"}) {
+		t.Errorf("dependences %v instead of target ones", deps)
+	}
+	if process.ReadSyncMetadata(&replica) != nil {
+		t.Errorf("sync metadata of source is copied: %s", replica.Description)
+	}
+}
@@ -0,0 +1,222 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  runner.go - process lists of selected kinds
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+)
+
+// Conflict policies - what to do if list was changed after it was read
+const (
+	ConflictRecompute = "recompute"
+	ConflictSkip      = "skip"
+	ConflictAbort     = "abort"
+)
+
+// MaxRecompute - number of attempts to process lists of one kind with recompute policy
+const MaxRecompute = 3
+
+var errConflict = errors.New("list was changed during processing")
+
+// Runner - populates lists with content of included lists
+type Runner struct {
+	ws       *c1ews.Client
+	DryRun   bool
+	Conflict string
+	// BackupDir - directory to save lists before modification. Empty to disable
+	BackupDir string
+	Limits    process.Limits
+	// Force - modify lists even if limits are exceeded
+	Force bool
+	// Diffs - changes of all processed lists
+	Diffs []process.ListDiff
+	// Written - modified lists as returned by server
+	Written []c1ews.ListResponse
+}
+
+func NewRunner(ws *c1ews.Client) *Runner {
+	return &Runner{
+		ws:       ws,
+		Conflict: ConflictRecompute,
+	}
+}
+
+// SetConflictPolicy - set action for lists changed during processing
+func (r *Runner) SetConflictPolicy(policy string) error {
+	switch policy {
+	case ConflictRecompute, ConflictSkip, ConflictAbort:
+		r.Conflict = policy
+		return nil
+	}
+	return fmt.Errorf("%s: unknown conflict policy", policy)
+}
+
+// Run - process given kinds of lists. Return highest exit code.
+// Changes of all kinds are computed and checked against limits before
+// any list is modified
+func (r *Runner) Run(kinds []*c1ews.ListKind) int {
+	returnCode := 0
+	var computed []*c1ews.ListKind
+	var processes []*process.Process
+	for _, kind := range kinds {
+		log.Printf("%s: Start", kind.Name)
+		p, rc := Compute(r.ws, kind)
+		if rc != 0 {
+			returnCode = maxCode(returnCode, rc)
+			continue
+		}
+		returnCode = maxCode(returnCode, ManualEditCode(p))
+		computed = append(computed, kind)
+		processes = append(processes, p)
+		r.Diffs = append(r.Diffs, p.Diff()...)
+	}
+	if rc := r.checkLimits(); rc != 0 {
+		return maxCode(returnCode, rc)
+	}
+	for i, kind := range computed {
+		returnCode = maxCode(returnCode, r.apply(kind, processes[i]))
+	}
+	return returnCode
+}
+
+// ProcessList - process all lists of given kind
+func (r *Runner) ProcessList(kind *c1ews.ListKind) int {
+	return r.Run([]*c1ews.ListKind{kind})
+}
+
+// checkLimits - return error code if changes exceed limits and are not forced
+func (r *Runner) checkLimits() int {
+	err := r.Limits.Check(r.Diffs)
+	if err == nil {
+		return 0
+	}
+	if r.Force {
+		log.Printf("%v. Continue (forced)", err)
+		return 0
+	}
+	log.Printf("%v. Use --force option to proceed", err)
+	return RCLimitExceeded
+}
+
+// apply - modify lists of given kind according to processing results
+func (r *Runner) apply(kind *c1ews.ListKind, p *process.Process) int {
+	for attempt := 1; ; attempt++ {
+		rc, err := r.modify(kind, p)
+		if !errors.Is(err, errConflict) {
+			return rc
+		}
+		if r.Conflict == ConflictAbort || attempt == MaxRecompute {
+			log.Printf("%s: %v. Abort", kind.Name, err)
+			return RCConflict
+		}
+		log.Printf("%s: %v. Recompute", kind.Name, err)
+		p, rc = Compute(r.ws, kind)
+		if rc != 0 {
+			return rc
+		}
+		diffs := r.Diffs[:0]
+		for _, d := range r.Diffs {
+			if d.Kind != kind.ID {
+				diffs = append(diffs, d)
+			}
+		}
+		r.Diffs = append(diffs, p.Diff()...)
+		if rc := r.checkLimits(); rc != 0 {
+			return rc
+		}
+	}
+}
+
+// modify - change lists of given kind
+func (r *Runner) modify(kind *c1ews.ListKind, p *process.Process) (int, error) {
+	name := kind.Name
+	observed := make(map[int]*c1ews.ListResponse)
+	for i, l := range p.Observed() {
+		observed[l.ID] = &p.Observed()[i]
+	}
+	if !r.DryRun {
+		if rc := Backup(r.BackupDir, r.ws.Host, kind, Changed(p)); rc != 0 {
+			return rc, nil
+		}
+	}
+	count := 0
+	err := p.IterateChanged(func(list *c1ews.ListResponse) error {
+		count++
+		log.Printf("%s: modify %s", name, list.Name)
+		if r.DryRun {
+			return nil
+		}
+		err := r.checkConflict(kind, observed[list.ID])
+		if errors.Is(err, errConflict) && r.Conflict == ConflictSkip {
+			log.Printf("%s: %v. Skip", name, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l := process.ListFromResponse(list)
+		written, err := r.ws.ModifyList(context.TODO(), kind, list.ID, l)
+		if err != nil {
+			return err
+		}
+		r.Written = append(r.Written, *written)
+		return nil
+	})
+	if errors.Is(err, errConflict) {
+		return RCConflict, err
+	}
+	if err != nil {
+		log.Printf("%s: %v", name, err)
+		return RCAPIError, nil
+	}
+	if count == 0 {
+		log.Printf("%s: No modifications", name)
+	}
+	return 0, nil
+}
+
+// maxCode - return largest of two exit codes
+func maxCode(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// checkConflict - return errConflict if list was changed since it was read
+func (r *Runner) checkConflict(kind *c1ews.ListKind, observed *c1ews.ListResponse) error {
+	current, err := r.ws.DescribeList(context.TODO(), kind, observed.ID)
+	if err != nil {
+		return err
+	}
+	if process.Fingerprint(current) != process.Fingerprint(observed) {
+		return fmt.Errorf("%s: %w", observed.Name, errConflict)
+	}
+	return nil
+}
+
+// Changed - return lists that are going to be changed in state before processing
+func Changed(p *process.Process) (result []c1ews.ListResponse) {
+	observed := make(map[int]c1ews.ListResponse)
+	for _, l := range p.Observed() {
+		observed[l.ID] = l
+	}
+	_ = p.IterateChanged(func(list *c1ews.ListResponse) error {
+		result = append(result, observed[list.ID])
+		return nil
+	})
+	return
+}
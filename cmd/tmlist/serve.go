@@ -0,0 +1,212 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  serve.go - run lists processing periodically in long-lived process
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagInterval   = "interval"
+	flagJitter     = "jitter"
+	flagMaxBackoff = "max_backoff"
+	flagStatusFile = "status_file"
+	flagPoll       = "poll"
+)
+
+// Status - state of scheduler saved to status file after each run. Times
+// of events that did not happen yet are nil, so they are omitted from file
+type Status struct {
+	Started             time.Time  `json:"started"`
+	Runs                int        `json:"runs"`
+	LastStart           *time.Time `json:"last_start,omitempty"`
+	LastEnd             *time.Time `json:"last_end,omitempty"`
+	LastReturnCode      int        `json:"last_return_code"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+}
+
+// Scheduler - calls function periodically. Next run is started only after
+// previous one is finished, so runs never overlap
+type Scheduler struct {
+	Interval time.Duration
+	// Jitter - maximum random delay added to each interval
+	Jitter time.Duration
+	// MaxBackoff - maximum delay after consecutive failures
+	MaxBackoff time.Duration
+	// StatusFile - file to save Status to. Empty to disable
+	StatusFile string
+	// Run - function to call. Non zero return code means failure
+	Run    func() int
+	Status Status
+	rand   *rand.Rand
+}
+
+func NewScheduler(interval time.Duration, run func() int) *Scheduler {
+	return &Scheduler{
+		Interval:   interval,
+		MaxBackoff: interval,
+		Run:        run,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Delay - return time to wait before next run. Interval is doubled after
+// each consecutive failure up to MaxBackoff
+func (s *Scheduler) Delay(failures int) time.Duration {
+	delay := s.Interval
+	for i := 0; i < failures && delay < s.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.MaxBackoff {
+		delay = s.MaxBackoff
+	}
+	if delay < s.Interval {
+		delay = s.Interval
+	}
+	if s.Jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(s.Jitter)))
+	}
+	return delay
+}
+
+// Loop - call Run function until context is canceled. Run in progress
+// is never interrupted, so lists are not left partially modified
+func (s *Scheduler) Loop(ctx context.Context) {
+	s.Status = Status{Started: time.Now()}
+	for {
+		start := time.Now()
+		s.Status.LastStart = &start
+		rc := s.Run()
+		end := time.Now()
+		s.Status.LastEnd = &end
+		s.Status.Runs++
+		s.Status.LastReturnCode = rc
+		if rc == 0 {
+			s.Status.LastSuccess = &end
+			s.Status.ConsecutiveFailures = 0
+		} else {
+			s.Status.ConsecutiveFailures++
+		}
+		delay := s.Delay(s.Status.ConsecutiveFailures)
+		next := end.Add(delay)
+		s.Status.NextRun = &next
+		if rc != 0 {
+			log.Printf("Run failed with return code %d (%d in a row). Next run in %v",
+				rc, s.Status.ConsecutiveFailures, delay.Round(time.Second))
+		} else {
+			log.Printf("Next run in %v", delay.Round(time.Second))
+		}
+		s.saveStatus()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.Status.NextRun = nil
+			s.saveStatus()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// saveStatus - write status to file if status file is set. File is
+// replaced atomically, so monitoring never reads partial content
+func (s *Scheduler) saveStatus() {
+	if s.StatusFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(&s.Status, "", "  ")
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.StatusFile), filepath.Base(s.StatusFile)+".*")
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.StatusFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("%s: %v", s.StatusFile, err)
+	}
+}
+
+func ServeFlags(fs *pflag.FlagSet) {
+	fs.Duration(flagInterval, 15*time.Minute, "Time between runs")
+	fs.Duration(flagJitter, time.Minute, "Maximum random delay added to interval")
+	fs.Duration(flagMaxBackoff, time.Hour, "Maximum time between runs after consecutive failures")
+	fs.String(flagStatusFile, "", "JSON file to save state of runs to")
+	fs.Duration(flagPoll, 0, "Check lists for changes with given period and process only changed ones instead of running every interval")
+}
+
+// ServeCommand - run lists processing of selected profiles periodically
+// until SIGTERM or SIGINT is received
+func ServeCommand(args []string) int {
+	interval := viper.GetDuration(flagInterval)
+	poll := viper.GetDuration(flagPoll)
+	if interval <= 0 || poll < 0 {
+		log.Printf("%s should be positive and %s should not be negative", flagInterval, flagPoll)
+		return RCCommandLine
+	}
+	if viper.GetDuration(flagJitter) < 0 || viper.GetDuration(flagMaxBackoff) < 0 {
+		log.Printf("%s and %s should not be negative", flagJitter, flagMaxBackoff)
+		return RCCommandLine
+	}
+	if _, err := SelectedProfiles(); err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	command := &Command{Name: "run", Run: RunCommand}
+	if poll > 0 {
+		interval = poll
+		command = &Command{Name: "watch", Run: NewWatcher().Run}
+	}
+	s := NewScheduler(interval, func() int {
+		return RunProfiles(command, args)
+	})
+	s.Jitter = viper.GetDuration(flagJitter)
+	s.MaxBackoff = viper.GetDuration(flagMaxBackoff)
+	s.StatusFile = viper.GetString(flagStatusFile)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Run in progress is finished; second signal terminates process immediately
+		stop()
+	}()
+	if poll > 0 {
+		log.Printf("Serve: check lists for changes every %v", poll)
+	} else {
+		log.Printf("Serve: run every %v", interval)
+	}
+	s.Loop(ctx)
+	log.Print("Serve: stopped")
+	return 0
+}
@@ -0,0 +1,138 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  serve_test.go - tests for serve command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestSchedulerDelay(t *testing.T) {
+	s := NewScheduler(time.Minute, nil)
+	s.MaxBackoff = 5 * time.Minute
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 5 * time.Minute},
+		{10, 5 * time.Minute},
+	}
+	for _, tc := range testCases {
+		if actual := s.Delay(tc.failures); actual != tc.expected {
+			t.Errorf("%d failures: %v instead of %v", tc.failures, actual, tc.expected)
+		}
+	}
+	s.MaxBackoff = 0
+	if actual := s.Delay(3); actual != time.Minute {
+		t.Errorf("%v instead of %v", actual, time.Minute)
+	}
+	s.Jitter = time.Second
+	for i := 0; i < 10; i++ {
+		actual := s.Delay(0)
+		if actual < time.Minute || actual >= time.Minute+time.Second {
+			t.Errorf("%v is out of range", actual)
+		}
+	}
+}
+
+func TestSchedulerLoop(t *testing.T) {
+	codes := []int{RCAPIError, RCAPIError, 0}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := 0
+	running := false
+	s := NewScheduler(time.Millisecond, func() int {
+		if running {
+			t.Errorf("runs overlap")
+		}
+		running = true
+		defer func() { running = false }()
+		rc := codes[runs]
+		runs++
+		if runs == len(codes) {
+			cancel()
+		}
+		return rc
+	})
+	s.MaxBackoff = 4 * time.Millisecond
+	s.StatusFile = filepath.Join(t.TempDir(), "status.json")
+	done := make(chan struct{})
+	go func() {
+		s.Loop(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("loop is not stopped")
+	}
+	data, err := os.ReadFile(s.StatusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Runs != 3 || status.ConsecutiveFailures != 0 || status.LastReturnCode != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.LastSuccess == nil || status.NextRun != nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if strings.Contains(string(data), "next_run") {
+		t.Errorf("next run is saved after stop: %s", data)
+	}
+}
+
+func TestServeInvalidClient(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set(flagAddress, "https://localhost")
+	viper.Set(flagAPIKey, "key")
+	viper.Set(flagCACert, filepath.Join(t.TempDir(), "missing.pem"))
+	command := &Command{Name: "run", Run: RunCommand}
+	if rc := RunProfiles(command, nil); rc != RCCommandLine {
+		t.Errorf("return code %d instead of %d", rc, RCCommandLine)
+	}
+	viper.Set(flagCACert, "")
+	viper.Set(flagAPIKey, "")
+	if rc := NewWatcher().Run(nil); rc != RCCommandLine {
+		t.Errorf("return code %d instead of %d", rc, RCCommandLine)
+	}
+}
+
+func TestSchedulerStatusWithoutSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler(time.Hour, func() int {
+		cancel()
+		return RCAPIError
+	})
+	s.StatusFile = filepath.Join(t.TempDir(), "status.json")
+	s.Loop(ctx)
+	data, err := os.ReadFile(s.StatusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "last_success") || strings.Contains(string(data), "0001-01-01") {
+		t.Errorf("unset times are saved: %s", data)
+	}
+}
@@ -0,0 +1,233 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  sync.go - make lists match directory of list files
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/listfile"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	flagFrom   = "from"
+	flagDelete = "delete"
+)
+
+// SyncPlan - changes required to make lists of one kind match list files
+type SyncPlan struct {
+	Kind   *c1ews.ListKind
+	Create []c1ews.ListResponse
+	// Modify - desired state of existing lists
+	Modify []c1ews.ListResponse
+	Delete []c1ews.ListResponse
+	// Before - state of modified lists before sync
+	Before []c1ews.ListResponse
+	Diffs  []process.ListDiff
+}
+
+// Empty - return true if no changes are required
+func (s *SyncPlan) Empty() bool {
+	return len(s.Create)+len(s.Modify)+len(s.Delete) == 0
+}
+
+func SyncFlags(fs *pflag.FlagSet) {
+	fs.String(flagFrom, "lists", "Directory with list files")
+	fs.Bool(flagDelete, false, "Delete lists missing in directory (only managed by instance if it is set)")
+}
+
+// SyncCommand - create, modify and delete lists of selected kinds to match
+// directory of list files
+func SyncCommand(args []string) (exitCode int) {
+	dir := viper.GetString(flagFrom)
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	var plans []*SyncPlan
+	var diffs []process.ListDiff
+	for _, kind := range SelectedKinds() {
+		if !listfile.HasKind(dir, kind) {
+			log.Printf("%s: %s directory not found. Skip", kind.Name, kind.ID)
+			continue
+		}
+		files, err := listfile.ReadKind(dir, kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCOther
+		}
+		current, err := ws.ListLists(context.TODO(), kind)
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return RCAPIError
+		}
+		plan, err := ComputeSync(kind, files, current, viper.GetBool(flagDelete))
+		if err != nil {
+			log.Printf("%s: %v", kind.Name, err)
+			return ReturnCode(err)
+		}
+		plans = append(plans, plan)
+		diffs = append(diffs, plan.Diffs...)
+	}
+	r := NewRunner(ws)
+	ConfigureLimits(r)
+	r.Diffs = diffs
+	if rc := r.checkLimits(); rc != 0 {
+		return rc
+	}
+	if viper.GetBool(flagDryRun) {
+		for _, plan := range plans {
+			LogSyncPlan(plan)
+		}
+		if err := WriteDiff(diffs); err != nil {
+			log.Print(err)
+			return RCOther
+		}
+		return 0
+	}
+	for _, plan := range plans {
+		if rc := ApplySync(ws, viper.GetString(flagBackupDir), plan); rc != 0 {
+			return rc
+		}
+	}
+	return 0
+}
+
+// ComputeSync - return changes required to make current lists match files.
+// Includes are resolved among file lists and lists existing only on server
+// that are not going to be deleted. Lists are matched by name. IDs in files
+// are ignored
+func ComputeSync(kind *c1ews.ListKind, files []*listfile.File, current []c1ews.ListResponse, deleteMissing bool) (*SyncPlan, error) {
+	existing := make(map[string]c1ews.ListResponse)
+	for _, l := range current {
+		existing[l.Name] = l
+	}
+	defined := make(map[string]bool)
+	var in []c1ews.ListResponse
+	for _, f := range files {
+		l := f.ListResponse()
+		l.ID = existing[l.Name].ID
+		defined[l.Name] = true
+		in = append(in, l)
+	}
+	instance := viper.GetString(flagInstance)
+	plan := &SyncPlan{Kind: kind}
+	for _, l := range current {
+		if defined[l.Name] {
+			continue
+		}
+		if deleteMissing && (instance == "" || process.ManagedBy(&l) == instance) {
+			l := l
+			plan.Delete = append(plan.Delete, l)
+			plan.Diffs = append(plan.Diffs, kindDiff(kind, &l, &c1ews.ListResponse{ID: l.ID, Name: l.Name}))
+			continue
+		}
+		in = append(in, l)
+	}
+	p, err := NewKindProcess(kind, in)
+	if err != nil {
+		return nil, err
+	}
+	// List files are the source of truth, so manual edits are not preserved
+	if err := p.SetManualEditPolicy(process.ManualEditOverwrite, ""); err != nil {
+		return nil, err
+	}
+	if err := p.Process(); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		desired := *p.FindList(f.Name)
+		before, ok := existing[f.Name]
+		if !ok {
+			plan.Create = append(plan.Create, desired)
+			plan.Diffs = append(plan.Diffs, kindDiff(kind, &c1ews.ListResponse{}, &desired))
+			continue
+		}
+		if sameList(&before, &desired) {
+			continue
+		}
+		plan.Modify = append(plan.Modify, desired)
+		plan.Before = append(plan.Before, before)
+		plan.Diffs = append(plan.Diffs, kindDiff(kind, &before, &desired))
+	}
+	return plan, nil
+}
+
+// sameList - return true if lists differ only in order of items or sync metadata
+func sameList(a, b *c1ews.ListResponse) bool {
+	ac, bc := *a, *b
+	ac.Items = append([]string{}, a.Items...)
+	bc.Items = append([]string{}, b.Items...)
+	sort.Strings(ac.Items)
+	sort.Strings(bc.Items)
+	return process.EqualIgnoringSyncMetadata(&ac, &bc)
+}
+
+func kindDiff(kind *c1ews.ListKind, before, after *c1ews.ListResponse) process.ListDiff {
+	d := process.Diff(before, after)
+	d.Kind = kind.ID
+	return d
+}
+
+// LogSyncPlan - log changes of sync plan
+func LogSyncPlan(plan *SyncPlan) {
+	name := plan.Kind.Name
+	for _, l := range plan.Create {
+		log.Printf("%s: create %s", name, l.Name)
+	}
+	for _, l := range plan.Modify {
+		log.Printf("%s: modify %s", name, l.Name)
+	}
+	for _, l := range plan.Delete {
+		log.Printf("%s: delete %s", name, l.Name)
+	}
+	if plan.Empty() {
+		log.Printf("%s: No modifications", name)
+	}
+}
+
+// ApplySync - create, modify and delete lists according to sync plan
+func ApplySync(ws *c1ews.Client, backupDir string, plan *SyncPlan) int {
+	LogSyncPlan(plan)
+	kind := plan.Kind
+	changed := append(append([]c1ews.ListResponse{}, plan.Before...), plan.Delete...)
+	if rc := Backup(backupDir, ws.Host, kind, changed); rc != 0 {
+		return rc
+	}
+	for i := range plan.Create {
+		if _, err := ws.CreateList(context.TODO(), kind, process.ListFromResponse(&plan.Create[i])); err != nil {
+			log.Printf("%s: %s: %v", kind.Name, plan.Create[i].Name, err)
+			return RCAPIError
+		}
+	}
+	for i := range plan.Modify {
+		l := &plan.Modify[i]
+		if _, err := ws.ModifyList(context.TODO(), kind, l.ID, process.ListFromResponse(l)); err != nil {
+			log.Printf("%s: %s: %v", kind.Name, l.Name, err)
+			return RCAPIError
+		}
+	}
+	for _, l := range plan.Delete {
+		if err := ws.DeleteList(context.TODO(), kind, l.ID); err != nil {
+			log.Printf("%s: %s: %v", kind.Name, l.Name, err)
+			return RCAPIError
+		}
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  sync_test.go - tests for sync command
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/listfile"
+	"github.com/spf13/viper"
+)
+
+func writeListFile(t *testing.T, dir string, f *listfile.File) {
+	t.Helper()
+	path := filepath.Join(dir, c1ews.DirectoryLists.ID, listfile.FileName(f.Name))
+	if err := listfile.WriteFile(path, f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	dir := t.TempDir()
+	if rc := Export(ws, dir, c1ews.ListKinds); rc != 0 {
+		t.Fatalf("export return code %d", rc)
+	}
+	viper.Set(flagFrom, dir)
+	viper.Set(flagBackupDir, "")
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := SyncCommand(nil); rc != 0 {
+		t.Fatalf("sync return code %d", rc)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected := []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	writeListFile(t, dir, &listfile.File{Name: "Linux", Items: []string{"/tmp"}})
+	writeListFile(t, dir, &listfile.File{
+		Name:        "Database Servers",
+		Description: "Include: Linux\nInclude: Windows",
+	})
+	if err := os.Remove(filepath.Join(dir, "dir", "SQL_Server.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set(flagDryRun, true)
+	before := s.Lists(c1ews.DirectoryLists)
+	if rc := SyncCommand(nil); rc != 0 {
+		t.Fatalf("dry sync return code %d", rc)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("lists changed in dry run")
+	}
+	viper.Set(flagDryRun, false)
+	viper.Set(flagDelete, true)
+	if rc := SyncCommand(nil); rc != 0 {
+		t.Fatalf("sync return code %d", rc)
+	}
+	if s.FindList(c1ews.DirectoryLists, "Linux") == nil {
+		t.Errorf("list is not created")
+	}
+	if s.FindList(c1ews.DirectoryLists, "SQL Server") != nil {
+		t.Errorf("list is not deleted")
+	}
+	actual = s.FindList(c1ews.DirectoryLists, "Database Servers").Items
+	expected = []string{"/tmp", `C:\Windows\Temp`, `C:\pagefile.sys`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	before = s.Lists(c1ews.DirectoryLists)
+	if rc := SyncCommand(nil); rc != 0 {
+		t.Fatalf("repeated sync return code %d", rc)
+	}
+	if !reflect.DeepEqual(before, s.Lists(c1ews.DirectoryLists)) {
+		t.Errorf("repeated sync changed lists")
+	}
+}
+
+func TestSyncBrokenInclude(t *testing.T) {
+	s, _ := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeListFile(t, dir, &listfile.File{Name: "Broken", Description: "Include: Missing"})
+	viper.Set(flagFrom, dir)
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	if rc := SyncCommand(nil); rc != RCListNotFound {
+		t.Errorf("return code %d instead of %d", rc, RCListNotFound)
+	}
+}
+
+func TestSyncEmptyList(t *testing.T) {
+	s, ws := newServer(t)
+	configureServer(t, s.URL, s.APIKey)
+	dir := t.TempDir()
+	if rc := Export(ws, dir, c1ews.ListKinds); rc != 0 {
+		t.Fatalf("export return code %d", rc)
+	}
+	path := filepath.Join(dir, c1ews.FileExtensionLists.ID, listfile.FileName("Logs"))
+	if err := listfile.WriteFile(path, &listfile.File{Name: "Logs", Items: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set(flagFrom, dir)
+	viper.Set(flagBackupDir, "")
+	viper.Set(c1ews.FileExtensionLists.ID, true)
+	if rc := SyncCommand(nil); rc != 0 {
+		t.Fatalf("sync return code %d", rc)
+	}
+	logs := s.FindList(c1ews.FileExtensionLists, "Logs")
+	if len(logs.Items) != 0 || logs.Description != "" {
+		t.Errorf("list is not cleared: %+v", logs)
+	}
+}
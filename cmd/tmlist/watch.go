@@ -0,0 +1,152 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  watch.go - process lists only when they are changed
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"github.com/spf13/viper"
+)
+
+// Watcher - polls lists and processes only kinds with changed lists.
+// Only changed lists and lists depending on them are modified
+type Watcher struct {
+	// baseline - fingerprints of lists by name for each profile and kind
+	// as they were after last successful processing
+	baseline map[string]map[string]string
+}
+
+func NewWatcher() *Watcher {
+	return &Watcher{
+		baseline: make(map[string]map[string]string),
+	}
+}
+
+// Run - poll all selected kinds of lists of current profile. Kinds are
+// processed completely on first run. Return highest exit code
+func (w *Watcher) Run(args []string) (exitCode int) {
+	ws, err := NewClient()
+	if err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	defer func() {
+		exitCode = maxCode(exitCode, SaveClient(ws))
+	}()
+	r := NewRunner(ws)
+	r.DryRun = viper.GetBool(flagDryRun)
+	r.BackupDir = viper.GetString(flagBackupDir)
+	ConfigureLimits(r)
+	if err := r.SetConflictPolicy(viper.GetString(flagOnConflict)); err != nil {
+		log.Print(err)
+		return RCCommandLine
+	}
+	returnCode := 0
+	for _, kind := range SelectedKinds() {
+		returnCode = maxCode(returnCode, w.poll(r, kind))
+	}
+	if r.DryRun {
+		if err := WriteDiff(r.Diffs); err != nil {
+			log.Print(err)
+			return RCOther
+		}
+	}
+	return returnCode
+}
+
+// poll - process lists of given kind if any of them or any list of remote
+// profiles they include was changed since last successful processing.
+// Lists returned by server after modification become the baseline, so own
+// changes do not trigger processing
+func (w *Watcher) poll(r *Runner, kind *c1ews.ListKind) int {
+	key := currentProfile + "/" + kind.ID
+	lists, err := r.ws.ListLists(context.TODO(), kind)
+	if err != nil {
+		log.Printf("%s: %v", kind.Name, err)
+		return RCAPIError
+	}
+	p, err := NewKindProcess(kind, lists)
+	if err != nil {
+		log.Print(err)
+		return SetupReturnCode(err)
+	}
+	current := Fingerprints(lists)
+	// Remote lists are fingerprinted by qualified names, so they can not
+	// be mixed up with own lists
+	for name, fingerprint := range Fingerprints(p.RemoteLists()) {
+		current[name] = fingerprint
+	}
+	baseline, known := w.baseline[key]
+	var changed []string
+	if known {
+		changed = ChangedLists(baseline, current)
+		if len(changed) == 0 {
+			return 0
+		}
+		log.Printf("%s: Changed lists: %s", kind.Name, strings.Join(changed, ", "))
+	} else {
+		log.Printf("%s: Start", kind.Name)
+	}
+	if rc := RunProcess(kind, p); rc != 0 {
+		return rc
+	}
+	if known {
+		p.Restrict(p.Affected(changed))
+	}
+	r.Diffs = append(r.Diffs, p.Diff()...)
+	if rc := r.checkLimits(); rc != 0 {
+		return rc
+	}
+	written := len(r.Written)
+	if rc := r.apply(kind, p); rc != 0 {
+		return rc
+	}
+	if rc := ManualEditCode(p); rc != 0 {
+		// Kind is processed again on next poll, so frozen lists keep
+		// failing runs until manual changes are resolved
+		return rc
+	}
+	for i := range r.Written[written:] {
+		l := &r.Written[written+i]
+		current[l.Name] = process.Fingerprint(l)
+	}
+	w.baseline[key] = current
+	return 0
+}
+
+// Fingerprints - return fingerprints of lists by name
+func Fingerprints(lists []c1ews.ListResponse) map[string]string {
+	result := make(map[string]string, len(lists))
+	for i := range lists {
+		result[lists[i].Name] = process.Fingerprint(&lists[i])
+	}
+	return result
+}
+
+// ChangedLists - return sorted names of lists added, removed or modified
+func ChangedLists(before, after map[string]string) (result []string) {
+	for name, fingerprint := range after {
+		if before[name] != fingerprint {
+			result = append(result, name)
+		}
+	}
+	for name := range before {
+		if _, found := after[name]; !found {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return
+}
@@ -0,0 +1,114 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  watch_test.go - tests for processing of changed lists
+//
+//////////////////////////////////////////////////////////////////////////
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/spf13/viper"
+)
+
+func TestWatcher(t *testing.T) {
+	s, _ := newServer(t)
+	resetProfiles(t)
+	viper.Set(flagAddress, s.URL)
+	viper.Set(flagAPIKey, s.APIKey)
+	viper.Set(flagOnConflict, ConflictRecompute)
+	viper.Set(flagBackupDir, "")
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	w := NewWatcher()
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	expected := []string{`C:\Windows\Temp`, `C:\pagefile.sys`, `D:\MSSQL\Data`}
+	if actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	requests := s.Requests()
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if count := s.Requests() - requests; count != 1 {
+		t.Errorf("%d requests without changes", count)
+	}
+	windows := *s.FindList(c1ews.DirectoryLists, "Windows")
+	windows.Items = []string{`C:\Windows\Temp`}
+	s.SetList(c1ews.DirectoryLists, windows)
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	expected = []string{`C:\Windows\Temp`, `D:\MSSQL\Data`}
+	if actual := s.FindList(c1ews.DirectoryLists, "Database Servers").Items; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	requests = s.Requests()
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if count := s.Requests() - requests; count != 1 {
+		t.Errorf("own changes trigger processing: %d requests", count)
+	}
+}
+
+func TestWatcherRemote(t *testing.T) {
+	golden, _ := newServer(t)
+	tenant, _ := newServer(t)
+	resetProfiles(t)
+	viper.Set(flagAddress, tenant.URL)
+	viper.Set(flagAPIKey, tenant.APIKey)
+	viper.Set(flagOnConflict, ConflictRecompute)
+	viper.Set(flagBackupDir, "")
+	viper.Set(c1ews.DirectoryLists.ID, true)
+	viper.Set(profilesKey, map[string]any{
+		"golden": map[string]any{flagAddress: golden.URL, flagAPIKey: golden.APIKey},
+	})
+	commandLine[flagAddress] = true
+	t.Cleanup(func() { delete(commandLine, flagAddress) })
+	golden.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:  "Standard",
+		Items: []string{`C:\Golden`},
+	})
+	tenant.SetList(c1ews.DirectoryLists, c1ews.ListResponse{
+		Name:        "Tenant",
+		Description: "Include: golden/Standard",
+	})
+	w := NewWatcher()
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	requests := tenant.Requests()
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	if count := tenant.Requests() - requests; count != 1 {
+		t.Errorf("%d requests without changes", count)
+	}
+	standard := *golden.FindList(c1ews.DirectoryLists, "Standard")
+	standard.Items = []string{`C:\Golden`, `C:\Standard`}
+	golden.SetList(c1ews.DirectoryLists, standard)
+	if rc := w.Run(nil); rc != 0 {
+		t.Fatalf("return code %d", rc)
+	}
+	expected := []string{`C:\Golden`, `C:\Standard`}
+	if actual := tenant.FindList(c1ews.DirectoryLists, "Tenant").Items; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+}
+
+func TestChangedLists(t *testing.T) {
+	before := map[string]string{"a": "1", "b": "2", "c": "3"}
+	after := map[string]string{"a": "1", "b": "4", "d": "5"}
+	expected := []string{"b", "c", "d"}
+	if actual := ChangedLists(before, after); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v instead of %v", actual, expected)
+	}
+}
@@ -0,0 +1,355 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  store.go - lists kept in memory and served as lists API endpoints
+//
+//////////////////////////////////////////////////////////////////////////
+
+package offline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+// Store - lists kept in memory. It answers requests to lists endpoints,
+// so client can work without server (offline mode)
+type Store struct {
+	mu     sync.Mutex
+	lists  map[*c1ews.ListKind][]c1ews.ListResponse
+	nextID int
+}
+
+// NewStore - return store with given lists. Key is kind ID (dir, ext, file, ...).
+// Lists without ID get unique IDs
+func NewStore(lists map[string][]c1ews.ListResponse) (*Store, error) {
+	s := &Store{
+		lists:  make(map[*c1ews.ListKind][]c1ews.ListResponse),
+		nextID: 1,
+	}
+	for id, kindLists := range lists {
+		kind := c1ews.FindListKind(id)
+		if kind == nil {
+			return nil, fmt.Errorf("unknown list kind: %s", id)
+		}
+		for _, l := range kindLists {
+			if l.ID >= s.nextID {
+				s.nextID = l.ID + 1
+			}
+		}
+		s.lists[kind] = append(s.lists[kind], kindLists...)
+	}
+	for _, kind := range c1ews.ListKinds {
+		for i := range s.lists[kind] {
+			if s.lists[kind][i].ID == 0 {
+				s.lists[kind][i].ID = s.nextID
+				s.nextID++
+			}
+		}
+	}
+	return s, nil
+}
+
+// Lists - return copy of current lists of given kind
+func (s *Store) Lists(kind *c1ews.ListKind) []c1ews.ListResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]c1ews.ListResponse, len(s.lists[kind]))
+	copy(result, s.lists[kind])
+	return result
+}
+
+// SetList - replace list with the same ID or add new one
+func (s *Store) SetList(kind *c1ews.ListKind, list c1ews.ListResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.lists[kind] {
+		if s.lists[kind][i].ID == list.ID {
+			s.lists[kind][i] = list
+			return
+		}
+	}
+	if list.ID == 0 {
+		list.ID = s.nextID
+		s.nextID++
+	}
+	s.lists[kind] = append(s.lists[kind], list)
+}
+
+// Snapshot - return copy of lists of all kinds that have any lists.
+// Key is kind ID
+func (s *Store) Snapshot() map[string][]c1ews.ListResponse {
+	result := make(map[string][]c1ews.ListResponse)
+	for _, kind := range c1ews.ListKinds {
+		if lists := s.Lists(kind); len(lists) > 0 {
+			result[kind.ID] = lists
+		}
+	}
+	return result
+}
+
+// RoundTrip - answer request from the store without connecting to server,
+// so store can be used as transport of c1ews.Client
+func (s *Store) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := &responseBuffer{header: make(http.Header)}
+	s.ServeHTTP(w, req)
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.code, http.StatusText(w.code)),
+		StatusCode:    w.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP - serve request to lists endpoints
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	kind := findKindByPath(path[0])
+	if kind == nil {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, map[string][]c1ews.ListResponse{kind.ResponseKey: s.lists[kind]})
+	case len(path) == 1 && r.Method == http.MethodPost:
+		s.create(w, r, kind)
+	case len(path) == 2 && path[1] == "search" && r.Method == http.MethodPost:
+		s.search(w, r, kind)
+	case len(path) == 2:
+		id, err := strconv.Atoi(path[1])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid ID")
+			return
+		}
+		index := s.index(kind, id)
+		if index == -1 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("The %s does not exist.", kind.Name))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, &s.lists[kind][index])
+		case http.MethodPost:
+			s.modify(w, r, kind, index)
+		case http.MethodDelete:
+			s.lists[kind] = append(s.lists[kind][:index], s.lists[kind][index+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Store) index(kind *c1ews.ListKind, id int) int {
+	for i := range s.lists[kind] {
+		if s.lists[kind][i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// listRequest - body of create and modify requests. Pointers are used to
+// distinguish missing fields
+type listRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Items       *[]string `json:"items"`
+}
+
+func (s *Store) create(w http.ResponseWriter, r *http.Request, kind *c1ews.ListKind) {
+	var request listRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Name == nil || *request.Name == "" {
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	for _, l := range s.lists[kind] {
+		if l.Name == *request.Name {
+			writeError(w, http.StatusBadRequest, "Name already in use")
+			return
+		}
+	}
+	list := c1ews.ListResponse{ID: s.nextID, Items: []string{}}
+	s.nextID++
+	apply(&list, &request)
+	s.lists[kind] = append(s.lists[kind], list)
+	writeJSON(w, &list)
+}
+
+func (s *Store) modify(w http.ResponseWriter, r *http.Request, kind *c1ews.ListKind, index int) {
+	var request listRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	list := s.lists[kind][index]
+	apply(&list, &request)
+	s.lists[kind][index] = list
+	writeJSON(w, &list)
+}
+
+func apply(list *c1ews.ListResponse, request *listRequest) {
+	if request.Name != nil {
+		list.Name = *request.Name
+	}
+	if request.Description != nil {
+		list.Description = *request.Description
+	}
+	if request.Items != nil {
+		list.Items = append([]string{}, *request.Items...)
+	}
+}
+
+func (s *Store) search(w http.ResponseWriter, r *http.Request, kind *c1ews.ListKind) {
+	var filter c1ews.SearchFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := []c1ews.ListResponse{}
+	for _, l := range s.lists[kind] {
+		match, err := matchAll(&l, filter.SearchCriteria)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if match {
+			result = append(result, l)
+		}
+	}
+	if filter.SortByObjectID {
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].ID < result[j].ID
+		})
+	}
+	if filter.MaxItems > 0 && len(result) > filter.MaxItems {
+		result = result[:filter.MaxItems]
+	}
+	writeJSON(w, map[string][]c1ews.ListResponse{kind.ResponseKey: result})
+}
+
+func matchAll(l *c1ews.ListResponse, criteria []c1ews.SearchCriteria) (bool, error) {
+	for _, c := range criteria {
+		match, err := matchCriteria(l, &c)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCriteria(l *c1ews.ListResponse, c *c1ews.SearchCriteria) (bool, error) {
+	switch c.FieldName {
+	case "ID":
+		switch c.IDTest {
+		case "equal":
+			return l.ID == c.IDValue, nil
+		case "greater-than":
+			return l.ID > c.IDValue, nil
+		case "less-than":
+			return l.ID < c.IDValue, nil
+		}
+		return false, fmt.Errorf("unsupported idTest: %s", c.IDTest)
+	case "name":
+		if c.StringTest != "equal" && c.StringTest != "not-equal" {
+			return false, fmt.Errorf("unsupported stringTest: %s", c.StringTest)
+		}
+		match := l.Name == c.StringValue
+		if c.StringWildcards {
+			match = wildcard(c.StringValue).MatchString(l.Name)
+		}
+		return match == (c.StringTest == "equal"), nil
+	}
+	return false, fmt.Errorf("unsupported fieldName: %s", c.FieldName)
+}
+
+// wildcard - convert search pattern to regular expression
+func wildcard(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func findKindByPath(path string) *c1ews.ListKind {
+	for _, kind := range c1ews.ListKinds {
+		if kind.Path == path {
+			return kind
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&c1ews.WSError{Message: message})
+}
+
+// responseBuffer - response writer keeping response in memory
+type responseBuffer struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) Write(data []byte) (int, error) {
+	if b.code == 0 {
+		b.code = http.StatusOK
+	}
+	return b.body.Write(data)
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.code == 0 {
+		b.code = code
+	}
+}
@@ -0,0 +1,58 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  store_test.go - tests for lists kept in memory
+//
+//////////////////////////////////////////////////////////////////////////
+
+package offline_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/internal/offline"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestStore(t *testing.T) {
+	store, err := offline.NewStore(map[string][]c1ews.ListResponse{
+		"dir": {{Name: "Windows", Items: []string{`C:\Windows\Temp`}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := c1ews.NewWorkloadSecurity("key", "http://offline").SetTransport(store)
+	lists, err := ws.ListDirectoryLists(context.Background(), c1ews.NameMatches("Win%"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].ID == 0 {
+		t.Fatalf("unexpected lists: %v", lists)
+	}
+	if _, err := ws.ModifyDirectoryList(context.Background(), lists[0].ID, &c1ews.List{Name: "Windows"}); err != nil {
+		t.Fatal(err)
+	}
+	created, err := ws.CreateList(context.Background(), c1ews.DirectoryLists, &c1ews.List{Name: "Linux", Items: []string{"/tmp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.CreateList(context.Background(), c1ews.DirectoryLists, &c1ews.List{Name: "Linux"}); err == nil {
+		t.Errorf("duplicate name is accepted")
+	}
+	expected := map[string][]c1ews.ListResponse{
+		"dir": {
+			{ID: lists[0].ID, Name: "Windows", Items: []string{}},
+			{ID: created.ID, Name: "Linux", Items: []string{"/tmp"}},
+		},
+	}
+	if actual := store.Snapshot(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	if _, err := offline.NewStore(map[string][]c1ews.ListResponse{"unknown": nil}); err == nil {
+		t.Errorf("unknown kind is accepted")
+	}
+}
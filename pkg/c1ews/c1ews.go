@@ -15,6 +15,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,16 +25,28 @@ import (
 
 const Version = "v1"
 
+// DefaultPageSize - number of objects requested by one search query
+const DefaultPageSize = 1000
+
 type Client struct {
 	APIKey          string
 	Host            string
 	IgnoreTLSErrors bool
+	RootCAs         *x509.CertPool
+	Certificates    []tls.Certificate
+	Pins            [][]byte
+	PageSize        int
+	Recorder        *Cassette
+	Replayer        *Cassette
+	Transport       http.RoundTripper
+	Tracer          *Tracer
 }
 
 func NewWorkloadSecurity(APIKey string, Host string) *Client {
 	return &Client{
-		APIKey: APIKey,
-		Host:   Host,
+		APIKey:   APIKey,
+		Host:     Host,
+		PageSize: DefaultPageSize,
 	}
 }
 
@@ -42,14 +55,58 @@ func (c *Client) SetIgnoreTLSErrors(ignoreTLSErrors bool) *Client {
 	return c
 }
 
+// SetRecorder - record all requests and responses to cassette
+func (c *Client) SetRecorder(cassette *Cassette) *Client {
+	c.Recorder = cassette
+	return c
+}
+
+// SetReplayer - do not connect to server and answer all requests from cassette
+func (c *Client) SetReplayer(cassette *Cassette) *Client {
+	c.Replayer = cassette
+	return c
+}
+
+// SetTransport - do not connect to server and answer all requests using
+// given transport (e.g. lists kept in memory in offline mode)
+func (c *Client) SetTransport(transport http.RoundTripper) *Client {
+	c.Transport = transport
+	return c
+}
+
+// SetTracer - log all requests and responses
+func (c *Client) SetTracer(tracer *Tracer) *Client {
+	c.Tracer = tracer
+	return c
+}
+
+// SetPageSize - set maxItems value for search queries
+func (c *Client) SetPageSize(pageSize int) *Client {
+	c.PageSize = pageSize
+	return c
+}
+
 type WSError struct {
 	Message string `json:"message"`
 }
 
+// List - content of the list to create or modify. Description and items
+// are always sent, so empty ones clear them on the server. Empty name keeps
+// current name of modified list
 type List struct {
 	Name        string   `json:"name,omitempty"`
-	Description string   `json:"description,omitempty"`
-	Items       []string `json:"items,omitempty"`
+	Description string   `json:"description"`
+	Items       []string `json:"items"`
+}
+
+// marshalList - return request body for given list. Missing items are sent
+// as empty array
+func marshalList(list *List) ([]byte, error) {
+	body := *list
+	if body.Items == nil {
+		body.Items = []string{}
+	}
+	return json.Marshal(&body)
 }
 
 type ListResponse struct {
@@ -59,53 +116,135 @@ type ListResponse struct {
 	ID          int      `json:"ID"`
 }
 
-func (c *Client) ListDirectoryLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/directorylists")
+// SearchCriteria - one condition of search query
+type SearchCriteria struct {
+	FieldName       string `json:"fieldName"`
+	IDTest          string `json:"idTest,omitempty"`
+	IDValue         int    `json:"idValue,omitempty"`
+	StringTest      string `json:"stringTest,omitempty"`
+	StringValue     string `json:"stringValue,omitempty"`
+	StringWildcards bool   `json:"stringWildcards,omitempty"`
 }
 
-func (c *Client) ListFileExtensionLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/fileextensionlists")
+// SearchFilter - body of search query
+type SearchFilter struct {
+	MaxItems       int              `json:"maxItems,omitempty"`
+	SearchCriteria []SearchCriteria `json:"searchCriteria,omitempty"`
+	SortByObjectID bool             `json:"sortByObjectID,omitempty"`
 }
 
-func (c *Client) ListFileLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/filelists")
+// NameMatches - return criteria to filter objects by name on server side.
+// Pattern can include wildcards: % - any number of characters, _ - one character
+func NameMatches(pattern string) SearchCriteria {
+	return SearchCriteria{
+		FieldName:       "name",
+		StringTest:      "equal",
+		StringValue:     pattern,
+		StringWildcards: true,
+	}
 }
 
-func (c *Client) ListIPLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/iplists")
+// IDGreaterThan - return criteria to get objects with ID greater than given one
+func IDGreaterThan(id int) SearchCriteria {
+	return SearchCriteria{
+		FieldName: "ID",
+		IDTest:    "greater-than",
+		IDValue:   id,
+	}
 }
 
-func (c *Client) ListMACLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/maclists")
+// ListLists - return all lists of given kind matching criteria
+func (c *Client) ListLists(ctx context.Context, kind *ListKind, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.searchLists(ctx, kind.Path, kind.ResponseKey, criteria)
+}
+
+// DescribeList - return list of given kind with given ID
+func (c *Client) DescribeList(ctx context.Context, kind *ListKind, id int) (*ListResponse, error) {
+	var response ListResponse
+	err := c.query(ctx, "GET", fmt.Sprintf("/%s/%d", kind.Path, id), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
 }
 
-func (c *Client) ListPortLists(ctx context.Context) ([]ListResponse, error) {
-	return c.listLists(ctx, "/portlists")
+// ModifyList - replace description and items of the list of given kind
+// and rename it if name is given. Empty description or items clear them
+func (c *Client) ModifyList(ctx context.Context, kind *ListKind, id int, list *List) (*ListResponse, error) {
+	return c.modifyList(ctx, kind.Path, id, list)
+}
+
+// CreateList - create new list of given kind
+func (c *Client) CreateList(ctx context.Context, kind *ListKind, list *List) (*ListResponse, error) {
+	body, err := marshalList(list)
+	if err != nil {
+		return nil, err
+	}
+	var response ListResponse
+	err = c.query(ctx, "POST", "/"+kind.Path, bytes.NewBuffer(body), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteList - delete list of given kind with given ID
+func (c *Client) DeleteList(ctx context.Context, kind *ListKind, id int) error {
+	var response struct{}
+	return c.query(ctx, "DELETE", fmt.Sprintf("/%s/%d", kind.Path, id), nil, &response)
+}
+
+func (c *Client) ListDirectoryLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, DirectoryLists, criteria...)
+}
+
+func (c *Client) ListFileExtensionLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, FileExtensionLists, criteria...)
+}
+
+func (c *Client) ListFileLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, FileLists, criteria...)
+}
+
+func (c *Client) ListIPLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, IPLists, criteria...)
+}
+
+func (c *Client) ListMACLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, MACLists, criteria...)
+}
+
+func (c *Client) ListPortLists(ctx context.Context, criteria ...SearchCriteria) ([]ListResponse, error) {
+	return c.ListLists(ctx, PortLists, criteria...)
 }
 
 func (c *Client) ModifyDirectoryList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
-	return c.modifyList(ctx, "directorylists", id, dirList)
+	return c.ModifyList(ctx, DirectoryLists, id, dirList)
 }
 
 func (c *Client) ModifyFileExtensionList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
-	return c.modifyList(ctx, "fileextensionlists", id, dirList)
+	return c.ModifyList(ctx, FileExtensionLists, id, dirList)
 }
 
 func (c *Client) ModifyFileList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
-	return c.modifyList(ctx, "filelists", id, dirList)
+	return c.ModifyList(ctx, FileLists, id, dirList)
 }
 
 func (c *Client) ModifyIPList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
-	return c.modifyList(ctx, "iplists", id, dirList)
+	return c.ModifyList(ctx, IPLists, id, dirList)
 }
 
 func (c *Client) ModifyMACList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
-	return c.modifyList(ctx, "maclists", id, dirList)
+	return c.ModifyList(ctx, MACLists, id, dirList)
+}
+
+func (c *Client) ModifyPortList(ctx context.Context, id int, dirList *List) (*ListResponse, error) {
+	return c.ModifyList(ctx, PortLists, id, dirList)
 }
 
 func (c *Client) modifyList(ctx context.Context, path string, id int, dirList *List) (*ListResponse, error) {
 	url := fmt.Sprintf("/%s/%d", path, id)
-	body, err := json.Marshal(dirList)
+	body, err := marshalList(dirList)
 	if err != nil {
 		return nil, err
 	}
@@ -117,16 +256,40 @@ func (c *Client) modifyList(ctx context.Context, path string, id int, dirList *L
 	return &response, nil
 }
 
-func (c *Client) listLists(ctx context.Context, url string) ([]ListResponse, error) {
-	var response map[string][]ListResponse
-	err := c.query(ctx, "GET", url, nil, &response)
-	if err != nil {
-		return nil, err
-	}
-	for _, r := range response {
-		return r, nil
+// searchLists - return all lists matching criteria using /search endpoint.
+// Lists are requested page by page (sorted by ID) until last page is received
+func (c *Client) searchLists(ctx context.Context, path string, key string, criteria []SearchCriteria) ([]ListResponse, error) {
+	url := fmt.Sprintf("/%s/search", path)
+	var result []ListResponse
+	lastID := 0
+	for {
+		filter := SearchFilter{
+			MaxItems:       c.PageSize,
+			SearchCriteria: append([]SearchCriteria{}, criteria...),
+			SortByObjectID: true,
+		}
+		if lastID > 0 {
+			filter.SearchCriteria = append(filter.SearchCriteria, IDGreaterThan(lastID))
+		}
+		body, err := json.Marshal(&filter)
+		if err != nil {
+			return nil, err
+		}
+		var response map[string][]ListResponse
+		err = c.query(ctx, "POST", url, bytes.NewBuffer(body), &response)
+		if err != nil {
+			return nil, err
+		}
+		page, ok := response[key]
+		if !ok {
+			return nil, fmt.Errorf("missing %s in response data for %s", key, url)
+		}
+		result = append(result, page...)
+		if c.PageSize <= 0 || len(page) < c.PageSize {
+			return result, nil
+		}
+		lastID = page[len(page)-1].ID
 	}
-	return nil, fmt.Errorf("missing response data for %s", url)
 }
 
 type DescribeAPIKeyResponse struct {
@@ -155,6 +318,25 @@ func (c *Client) DescribeCurrentAPIKey(ctx context.Context) (*DescribeAPIKeyResp
 	return &response, nil
 }
 
+func (c *Client) transport() http.RoundTripper {
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: c.tlsConfig(),
+	}
+	if c.Replayer != nil {
+		transport = c.Replayer.Replayer()
+	}
+	if c.Transport != nil {
+		transport = c.Transport
+	}
+	if c.Recorder != nil {
+		transport = c.Recorder.Recorder(transport)
+	}
+	if c.Tracer != nil {
+		transport = c.Tracer.Transport(transport)
+	}
+	return transport
+}
+
 func (c *Client) query(ctx context.Context,
 	method string,
 	url string,
@@ -171,15 +353,12 @@ func (c *Client) query(ctx context.Context,
 	if requestBody != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: c.IgnoreTLSErrors}, //nolint
-	}
-	client := &http.Client{Transport: transport}
+	client := &http.Client{Transport: c.transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("HTTP request: %w", err)
 	}
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		var data bytes.Buffer
 		if _, err := io.Copy(&data, resp.Body); err != nil {
 			return fmt.Errorf("error body receive: %w", err)
@@ -191,7 +370,6 @@ func (c *Client) query(ctx context.Context,
 		return fmt.Errorf("code %d: %s", resp.StatusCode, wse.Message)
 	}
 	defer resp.Body.Close()
-	//io.Copy(os.Stdout, resp.Body)
 	err = json.NewDecoder(resp.Body).Decode(response)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return fmt.Errorf("response parse: %w", err)
@@ -0,0 +1,193 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  c1ews_test.go - tests for Workload Security API client
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews/c1ewstest"
+)
+
+func newServer(t *testing.T) *c1ewstest.Server {
+	t.Helper()
+	s, err := c1ewstest.NewServerFromFile("c1ewstest/testdata/lists.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestListListsPaging(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL).SetPageSize(1)
+	lists, err := ws.ListDirectoryLists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range lists {
+		names = append(names, l.Name)
+	}
+	expected := []string{"Windows", "SQL Server", "Database Servers"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("%v is not equal to %v", names, expected)
+	}
+	if s.Requests() != 4 {
+		t.Errorf("%d requests instead of 4", s.Requests())
+	}
+}
+
+func TestListListsExactPage(t *testing.T) {
+	lists := []c1ews.ListResponse{{ID: 3}, {ID: 5}, {ID: 8}, {ID: 13}}
+	var filters []c1ews.SearchFilter
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var filter c1ews.SearchFilter
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			t.Error(err)
+		}
+		filters = append(filters, filter)
+		page := []c1ews.ListResponse{}
+		for _, l := range lists {
+			if len(page) == filter.MaxItems {
+				break
+			}
+			last := filter.SearchCriteria
+			if len(last) > 0 && l.ID <= last[len(last)-1].IDValue {
+				continue
+			}
+			page = append(page, l)
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]c1ews.ListResponse{"portLists": page})
+	}))
+	t.Cleanup(s.Close)
+	ws := c1ews.NewWorkloadSecurity("key", s.URL).SetPageSize(2)
+	actual, err := ws.ListPortLists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, lists) {
+		t.Errorf("%v is not equal to %v", actual, lists)
+	}
+	if len(filters) != 3 {
+		t.Fatalf("%d requests instead of 3", len(filters))
+	}
+	for i, lastID := range []int{0, 5, 13} {
+		f := filters[i]
+		if f.MaxItems != 2 || !f.SortByObjectID {
+			t.Errorf("request %d: unexpected filter %+v", i, f)
+		}
+		if lastID == 0 && len(f.SearchCriteria) != 0 {
+			t.Errorf("request %d: unexpected criteria %+v", i, f.SearchCriteria)
+		}
+		if lastID != 0 && !reflect.DeepEqual(f.SearchCriteria, []c1ews.SearchCriteria{c1ews.IDGreaterThan(lastID)}) {
+			t.Errorf("request %d: criteria %+v instead of ID greater than %d", i, f.SearchCriteria, lastID)
+		}
+	}
+}
+
+func TestListListsNameFilter(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+	lists, err := ws.ListLists(context.Background(), c1ews.DirectoryLists, c1ews.NameMatches("%Server%"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Errorf("got %d lists instead of 2: %v", len(lists), lists)
+	}
+}
+
+func TestModifyList(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+	list := &c1ews.List{Items: []string{"a", "b"}}
+	_, err := ws.ModifyList(context.Background(), c1ews.DirectoryLists, 2, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := s.FindList(c1ews.DirectoryLists, "SQL Server")
+	if !reflect.DeepEqual(actual.Items, list.Items) {
+		t.Errorf("%v is not equal to %v", actual.Items, list.Items)
+	}
+	if actual.Description != "" {
+		t.Errorf("description is not cleared: %q", actual.Description)
+	}
+}
+
+func TestCreateDeleteList(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+	list := &c1ews.List{Name: "Linux", Items: []string{"/tmp"}}
+	created, err := ws.CreateList(context.Background(), c1ews.DirectoryLists, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || !reflect.DeepEqual(created.Items, list.Items) {
+		t.Errorf("unexpected response: %v", created)
+	}
+	if _, err := ws.CreateList(context.Background(), c1ews.DirectoryLists, list); err == nil {
+		t.Errorf("list with duplicate name is created")
+	}
+	if err := ws.DeleteList(context.Background(), c1ews.DirectoryLists, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if s.FindList(c1ews.DirectoryLists, "Linux") != nil {
+		t.Errorf("list is not deleted")
+	}
+	if err := ws.DeleteList(context.Background(), c1ews.DirectoryLists, created.ID); err == nil {
+		t.Errorf("error expected for missing list")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity("wrong:key", s.URL)
+	_, err := ws.ListFileExtensionLists(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("wrong API key: unexpected error %v", err)
+	}
+	ws = c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+	faults := []struct {
+		name   string
+		faults c1ewstest.Faults
+	}{
+		{"500", c1ewstest.Faults{InternalErrors: 1}},
+		{"429", c1ewstest.Faults{TooManyRequests: 1}},
+		{"malformed", c1ewstest.Faults{MalformedJSON: 1}},
+	}
+	for _, f := range faults {
+		s.SetFaults(f.faults)
+		if _, err := ws.ListFileExtensionLists(context.Background()); err == nil {
+			t.Errorf("%s: error expected", f.name)
+		}
+	}
+}
+
+func TestModifyListEmpty(t *testing.T) {
+	s := newServer(t)
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL)
+	logs := s.FindList(c1ews.FileExtensionLists, "Logs")
+	list := &c1ews.List{Name: logs.Name}
+	modified, err := ws.ModifyList(context.Background(), c1ews.FileExtensionLists, logs.ID, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified.Items) != 0 || modified.Description != "" {
+		t.Errorf("list is not cleared: %+v", modified)
+	}
+}
@@ -0,0 +1,205 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  server.go - in-memory fake of Workload Security API lists endpoints
+//  for tests
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ewstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mpkondrashin/tmlist/internal/offline"
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture - initial state of the server
+type Fixture struct {
+	APIKey string `yaml:"api_key"`
+	// Lists - lists of each kind. Key is kind ID (dir, ext, file, ...)
+	Lists map[string][]c1ews.ListResponse `yaml:"lists"`
+}
+
+// LoadFixture - read fixture from YAML file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// Faults - errors server should return. Each counter is number of
+// following requests to fail this way
+type Faults struct {
+	Latency         time.Duration
+	TooManyRequests int
+	InternalErrors  int
+	MalformedJSON   int
+}
+
+type Server struct {
+	*httptest.Server
+	APIKey string
+
+	store    *offline.Store
+	mu       sync.Mutex
+	faults   Faults
+	requests int
+	hook     func(r *http.Request)
+}
+
+// NewServer - start server populated with fixture content
+func NewServer(fixture *Fixture) (*Server, error) {
+	store, err := offline.NewStore(fixture.Lists)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		APIKey: fixture.APIKey,
+		store:  store,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s, nil
+}
+
+// NewServerFromFile - start server populated with content of YAML fixture file
+func NewServerFromFile(path string) (*Server, error) {
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewServer(fixture)
+}
+
+// SetFaults - set errors to be returned by following requests
+func (s *Server) SetFaults(faults Faults) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+	return s
+}
+
+// SetHook - call function before serving each request. It can be used
+// to simulate changes made by other users. Function is called without
+// server lock held, so it can call other server methods
+func (s *Server) SetHook(hook func(r *http.Request)) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hook = hook
+	return s
+}
+
+// Lists - return copy of current lists of given kind
+func (s *Server) Lists(kind *c1ews.ListKind) []c1ews.ListResponse {
+	return s.store.Lists(kind)
+}
+
+// FindList - return copy of list with given name or nil if not found
+func (s *Server) FindList(kind *c1ews.ListKind, name string) *c1ews.ListResponse {
+	for _, l := range s.Lists(kind) {
+		if l.Name == name {
+			return &l
+		}
+	}
+	return nil
+}
+
+// SetList - replace list with the same ID or add new one
+func (s *Server) SetList(kind *c1ews.ListKind, list c1ews.ListResponse) {
+	s.store.SetList(kind, list)
+}
+
+// Requests - return number of requests served
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	hook := s.hook
+	s.mu.Unlock()
+	if hook != nil {
+		hook(r)
+	}
+	if !s.check(w, r) {
+		return
+	}
+	s.store.ServeHTTP(w, r)
+}
+
+// check - count request, inject faults and check authentication. Return
+// false if request is answered
+func (s *Server) check(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.injectFault(w) {
+		return false
+	}
+	if r.Header.Get("api-secret-key") != s.APIKey && r.Header.Get("Authorization") != "ApiKey "+s.APIKey {
+		writeError(w, http.StatusUnauthorized, "Authentication required")
+		return false
+	}
+	if r.Header.Get("api-version") != c1ews.Version {
+		writeError(w, http.StatusBadRequest, "Unsupported api-version")
+		return false
+	}
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) == 2 && path[0] == "apikeys" && path[1] == "current" && r.Method == http.MethodGet {
+		writeJSON(w, &c1ews.DescribeAPIKeyResponse{KeyName: "c1ewstest", Active: true, ID: 1})
+		return false
+	}
+	return true
+}
+
+func (s *Server) injectFault(w http.ResponseWriter) bool {
+	if s.faults.Latency > 0 {
+		time.Sleep(s.faults.Latency)
+	}
+	switch {
+	case s.faults.TooManyRequests > 0:
+		s.faults.TooManyRequests--
+		writeError(w, http.StatusTooManyRequests, "Too many requests")
+	case s.faults.InternalErrors > 0:
+		s.faults.InternalErrors--
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+	case s.faults.MalformedJSON > 0:
+		s.faults.MalformedJSON--
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"malformed": [`)
+	default:
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&c1ews.WSError{Message: message})
+}
@@ -0,0 +1,157 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  cassette.go - record API traffic to file and replay it offline
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+const redacted = "REDACTED"
+
+// Interaction - one request and response pair
+type Interaction struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers,omitempty"`
+	RequestBody     string      `json:"request_body,omitempty"`
+	StatusCode      int         `json:"status_code"`
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	ResponseBody    string      `json:"response_body,omitempty"`
+}
+
+// Cassette - sequence of recorded interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+
+	mu   sync.Mutex
+	used []bool
+}
+
+func NewCassette() *Cassette {
+	return &Cassette{}
+}
+
+// LoadCassette - read cassette from file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save - write cassette to file
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Recorder - return transport that passes requests to next and records them
+func (c *Cassette) Recorder(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestBody, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		responseBody, err := readBody(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.Interactions = append(c.Interactions, Interaction{
+			Method:          req.Method,
+			URL:             req.URL.RequestURI(),
+			RequestHeaders:  redactHeaders(req.Header),
+			RequestBody:     string(RedactBody(requestBody)),
+			StatusCode:      resp.StatusCode,
+			ResponseHeaders: resp.Header.Clone(),
+			ResponseBody:    string(RedactBody(responseBody)),
+		})
+		return resp, nil
+	})
+}
+
+// Replayer - return transport that answers requests with recorded responses.
+// Interactions are matched by method, URL and request body in recorded order
+func (c *Cassette) Replayer() http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestBody, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if len(c.used) != len(c.Interactions) {
+			c.used = make([]bool, len(c.Interactions))
+		}
+		for i := range c.Interactions {
+			in := &c.Interactions[i]
+			if c.used[i] || in.Method != req.Method || in.URL != req.URL.RequestURI() || in.RequestBody != string(requestBody) {
+				continue
+			}
+			c.used[i] = true
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+				StatusCode:    in.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        in.ResponseHeaders.Clone(),
+				Body:          io.NopCloser(bytes.NewBufferString(in.ResponseBody)),
+				ContentLength: int64(len(in.ResponseBody)),
+				Request:       req,
+			}, nil
+		}
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.RequestURI(), ErrNoInteraction)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// readBody - read whole body and replace it with copy that can be read again
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewBuffer(data))
+	return data, nil
+}
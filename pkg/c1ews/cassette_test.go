@@ -0,0 +1,61 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  cassette_test.go - tests for record and replay of API traffic
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestRecordReplay(t *testing.T) {
+	s := newServer(t)
+	cassette := c1ews.NewCassette()
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL).SetRecorder(cassette)
+	expected, err := ws.ListDirectoryLists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), s.APIKey) {
+		t.Errorf("API Key is not redacted")
+	}
+	s.Close()
+
+	loaded, err := c1ews.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws = c1ews.NewWorkloadSecurity("", s.URL).SetReplayer(loaded)
+	actual, err := ws.ListDirectoryLists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+	_, err = ws.ListDirectoryLists(context.Background())
+	if !errors.Is(err, c1ews.ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  kind.go - registry of list kinds supported by Workload Security API
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidItem = errors.New("invalid item")
+
+// ListKind - descriptor of one kind of lists
+type ListKind struct {
+	// ID - short name of the kind used for command line options
+	ID string
+	// Name - human readable name of the kind
+	Name string
+	// Path - API endpoint path
+	Path string
+	// ResponseKey - name of the field holding lists in search response
+	ResponseKey string
+	// Exclusion - lists of this kind are used as anti-malware exclusions
+	Exclusion bool
+	// Validate - check single item of the list
+	Validate func(item string) error
+}
+
+var (
+	DirectoryLists = &ListKind{
+		ID:          "dir",
+		Name:        "Directory Lists",
+		Path:        "directorylists",
+		ResponseKey: "directoryLists",
+		Exclusion:   true,
+		Validate:    ValidateText,
+	}
+	FileExtensionLists = &ListKind{
+		ID:          "ext",
+		Name:        "File Extension Lists",
+		Path:        "fileextensionlists",
+		ResponseKey: "fileExtensionLists",
+		Exclusion:   true,
+		Validate:    ValidateText,
+	}
+	FileLists = &ListKind{
+		ID:          "file",
+		Name:        "File Lists",
+		Path:        "filelists",
+		ResponseKey: "fileLists",
+		Exclusion:   true,
+		Validate:    ValidateText,
+	}
+	IPLists = &ListKind{
+		ID:          "ip",
+		Name:        "IP Lists",
+		Path:        "iplists",
+		ResponseKey: "ipLists",
+		Validate:    ValidateIP,
+	}
+	MACLists = &ListKind{
+		ID:          "mac",
+		Name:        "MAC Lists",
+		Path:        "maclists",
+		ResponseKey: "macLists",
+		Validate:    ValidateMAC,
+	}
+	PortLists = &ListKind{
+		ID:          "port",
+		Name:        "Port Lists",
+		Path:        "portlists",
+		ResponseKey: "portLists",
+		Validate:    ValidatePort,
+	}
+)
+
+// ListKinds - all supported kinds of lists
+var ListKinds = []*ListKind{
+	DirectoryLists,
+	FileExtensionLists,
+	FileLists,
+	IPLists,
+	MACLists,
+	PortLists,
+}
+
+// FindListKind - return kind with given ID or nil if not found
+func FindListKind(id string) *ListKind {
+	for _, kind := range ListKinds {
+		if kind.ID == id {
+			return kind
+		}
+	}
+	return nil
+}
+
+func (k *ListKind) String() string {
+	return k.Name
+}
+
+// ValidateText - check item of directory, file extension or file list
+func ValidateText(item string) error {
+	if strings.TrimSpace(item) == "" {
+		return fmt.Errorf("empty item: %w", ErrInvalidItem)
+	}
+	if strings.ContainsAny(item, "\r\n") {
+		return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+	}
+	return nil
+}
+
+// ValidateIP - check item of IP list: single address, address/mask or range
+func ValidateIP(item string) error {
+	value := stripComment(item)
+	if from, to, ok := strings.Cut(value, "-"); ok {
+		if net.ParseIP(strings.TrimSpace(from)) == nil || net.ParseIP(strings.TrimSpace(to)) == nil {
+			return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+		}
+		return nil
+	}
+	if address, mask, ok := strings.Cut(value, "/"); ok {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+		}
+		if _, err := strconv.Atoi(mask); err == nil {
+			return nil
+		}
+		if net.ParseIP(mask) == nil {
+			return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+		}
+		return nil
+	}
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+	}
+	return nil
+}
+
+// ValidateMAC - check item of MAC list
+func ValidateMAC(item string) error {
+	if _, err := net.ParseMAC(stripComment(item)); err != nil {
+		return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+	}
+	return nil
+}
+
+// ValidatePort - check item of port list: single port or range
+func ValidatePort(item string) error {
+	value := stripComment(item)
+	from, to, ok := strings.Cut(value, "-")
+	if !validPort(from) || ok && !validPort(to) {
+		return fmt.Errorf("%q: %w", item, ErrInvalidItem)
+	}
+	return nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(strings.TrimSpace(port))
+	return err == nil && n >= 0 && n <= 65535
+}
+
+// stripComment - remove comment (starting with #) and spaces
+func stripComment(item string) string {
+	if comment := strings.Index(item, "#"); comment != -1 {
+		item = item[:comment]
+	}
+	return strings.TrimSpace(item)
+}
@@ -0,0 +1,62 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  kind_test.go - tests for functions in kind.go
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		item     string
+		valid    bool
+	}{
+		{"text", ValidateText, `C:\Windows`, true},
+		{"empty text", ValidateText, " ", false},
+		{"ip", ValidateIP, "10.0.0.1", true},
+		{"ip with comment", ValidateIP, "10.0.0.1 # gateway", true},
+		{"ip mask", ValidateIP, "10.0.0.0/8", true},
+		{"ip netmask", ValidateIP, "10.0.0.0/255.0.0.0", true},
+		{"ip range", ValidateIP, "10.0.0.1-10.0.0.9", true},
+		{"ipv6", ValidateIP, "fe80::1", true},
+		{"bad ip", ValidateIP, "10.0.0.256", false},
+		{"mac", ValidateMAC, "00:11:22:33:44:55", true},
+		{"bad mac", ValidateMAC, "00:11:22", false},
+		{"port", ValidatePort, "443", true},
+		{"port range", ValidatePort, "8000-8080", true},
+		{"bad port", ValidatePort, "70000", false},
+		{"bad port range", ValidatePort, "80-", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.validate(test.item)
+			if test.valid && err != nil {
+				t.Errorf("%q: unexpected error: %v", test.item, err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidItem) {
+				t.Errorf("%q: expected ErrInvalidItem, got %v", test.item, err)
+			}
+		})
+	}
+}
+
+func TestFindListKind(t *testing.T) {
+	for _, kind := range ListKinds {
+		if FindListKind(kind.ID) != kind {
+			t.Errorf("kind %s not found", kind.ID)
+		}
+	}
+	if FindListKind("unknown") != nil {
+		t.Errorf("unknown kind found")
+	}
+}
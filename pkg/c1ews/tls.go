@@ -0,0 +1,103 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  tls.go - TLS settings for on-premise Deep Security Managers
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrCertificatePin = errors.New("certificate fingerprint does not match any pinned value")
+
+// SetCACertFile - trust only certificate authorities from given PEM file
+func (c *Client) SetCACertFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("%s: no certificates found", path)
+	}
+	c.RootCAs = pool
+	return nil
+}
+
+// SetClientCertificate - use certificate and key from given PEM files for mutual TLS
+func (c *Client) SetClientCertificate(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	c.Certificates = []tls.Certificate{cert}
+	return nil
+}
+
+// SetPinnedCertificates - accept only server certificates with given SHA-256 fingerprints.
+// Fingerprint is hex string, colons and spaces are ignored. If no CA certificates
+// are set, pinning replaces certificate chain verification, so self-signed
+// certificates can be used
+func (c *Client) SetPinnedCertificates(fingerprints ...string) error {
+	c.Pins = nil
+	for _, f := range fingerprints {
+		pin, err := hex.DecodeString(NormalizeFingerprint(f))
+		if err != nil || len(pin) != sha256.Size {
+			return fmt.Errorf("%s: wrong SHA-256 fingerprint", f)
+		}
+		c.Pins = append(c.Pins, pin)
+	}
+	return nil
+}
+
+// NormalizeFingerprint - remove separators and convert fingerprint to lower case
+func NormalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+}
+
+// Fingerprint - return SHA-256 fingerprint of DER encoded certificate
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Client) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		InsecureSkipVerify: c.IgnoreTLSErrors, //nolint
+		RootCAs:            c.RootCAs,
+		Certificates:       c.Certificates,
+	}
+	if len(c.Pins) > 0 {
+		config.VerifyConnection = c.verifyPin
+		if c.RootCAs == nil {
+			config.InsecureSkipVerify = true //nolint
+		}
+	}
+	return config
+}
+
+// verifyPin - check fingerprint of server certificate
+func (c *Client) verifyPin(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return ErrCertificatePin
+	}
+	fingerprint := Fingerprint(state.PeerCertificates[0].Raw)
+	for _, pin := range c.Pins {
+		if fingerprint == hex.EncodeToString(pin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w", fingerprint, ErrCertificatePin)
+}
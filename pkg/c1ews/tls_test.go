@@ -0,0 +1,70 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  tls_test.go - tests for TLS settings
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keyName":"test"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestCACertFile(t *testing.T) {
+	s := newTLSServer(t)
+	ws := NewWorkloadSecurity("key", s.URL)
+	if _, err := ws.DescribeCurrentAPIKey(context.Background()); err == nil {
+		t.Errorf("unknown certificate authority accepted")
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.SetCACertFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.DescribeCurrentAPIKey(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPinnedCertificates(t *testing.T) {
+	s := newTLSServer(t)
+	ws := NewWorkloadSecurity("key", s.URL)
+	wrong := "00:11:22:33:44:55:66:77:88:99:AA:BB:CC:DD:EE:FF:00:11:22:33:44:55:66:77:88:99:AA:BB:CC:DD:EE:FF"
+	if err := ws.SetPinnedCertificates(wrong); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.DescribeCurrentAPIKey(context.Background()); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("expected ErrCertificatePin, got %v", err)
+	}
+	if err := ws.SetPinnedCertificates(Fingerprint(s.Certificate().Raw)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.DescribeCurrentAPIKey(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := ws.SetPinnedCertificates("not a fingerprint"); err == nil {
+		t.Errorf("wrong fingerprint accepted")
+	}
+}
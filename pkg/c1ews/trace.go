@@ -0,0 +1,138 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  trace.go - log API requests and responses with secrets redacted
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tracer - log method, URL, status and latency of every API call
+type Tracer struct {
+	// Bodies - log also headers and bodies of requests and responses
+	Bodies bool
+	// Logf - function to output log lines
+	Logf func(format string, v ...any)
+}
+
+func NewTracer(bodies bool) *Tracer {
+	return &Tracer{
+		Bodies: bodies,
+		Logf:   log.Printf,
+	}
+}
+
+// Transport - return transport that passes requests to next and logs them
+func (t *Tracer) Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Logf("--> %s %s", req.Method, req.URL)
+		if t.Bodies {
+			requestBody, err := readBody(&req.Body)
+			if err != nil {
+				return nil, err
+			}
+			t.logHeaders("-->", redactHeaders(req.Header))
+			t.logBody("-->", requestBody)
+		}
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		latency := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			t.Logf("<-- %s %s: %v (%v)", req.Method, req.URL, err, latency)
+			return nil, err
+		}
+		t.Logf("<-- %s %s: %s (%v)", req.Method, req.URL, resp.Status, latency)
+		if t.Bodies {
+			responseBody, err := readBody(&resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			t.logHeaders("<--", resp.Header)
+			t.logBody("<--", responseBody)
+		}
+		return resp, nil
+	})
+}
+
+func (t *Tracer) logHeaders(direction string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t.Logf("%s %s: %s", direction, name, strings.Join(header[name], ", "))
+	}
+}
+
+func (t *Tracer) logBody(direction string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	t.Logf("%s %s", direction, RedactBody(body))
+}
+
+// secretFields - JSON fields that should never be logged or saved
+var secretFields = map[string]struct{}{
+	"secretKey": {},
+}
+
+// RedactBody - replace values of secret fields in JSON body.
+// Non JSON bodies are returned as is
+func RedactBody(body []byte) []byte {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	if !redactValue(v) {
+		return body
+	}
+	result, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return result
+}
+
+// redactValue - replace secret fields recursively. Return true if something was replaced
+func redactValue(v any) (changed bool) {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if _, secret := secretFields[key]; secret {
+				value[key] = redacted
+				changed = true
+				continue
+			}
+			changed = redactValue(field) || changed
+		}
+	case []any:
+		for _, each := range value {
+			changed = redactValue(each) || changed
+		}
+	}
+	return
+}
+
+// redactHeaders - return copy of headers without API Key
+func redactHeaders(header http.Header) http.Header {
+	result := header.Clone()
+	if result.Get("Authorization") != "" {
+		result.Set("Authorization", "ApiKey "+redacted)
+	}
+	if result.Get("api-secret-key") != "" {
+		result.Set("api-secret-key", redacted)
+	}
+	return result
+}
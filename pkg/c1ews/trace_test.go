@@ -0,0 +1,60 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  trace_test.go - tests for API tracing
+//
+//////////////////////////////////////////////////////////////////////////
+
+package c1ews_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"not json", "secretKey", "secretKey"},
+		{"no secrets", `{"name":"a"}`, `{"name":"a"}`},
+		{"secret", `{"keyName":"a","secretKey":"xyz"}`, `{"keyName":"a","secretKey":"REDACTED"}`},
+		{"nested", `{"keys":[{"secretKey":"xyz"}]}`, `{"keys":[{"secretKey":"REDACTED"}]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := string(c1ews.RedactBody([]byte(test.input)))
+			if actual != test.expected {
+				t.Errorf("%s is not equal to %s", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestTracer(t *testing.T) {
+	s := newServer(t)
+	var lines []string
+	tracer := c1ews.NewTracer(true)
+	tracer.Logf = func(format string, v ...any) {
+		lines = append(lines, fmt.Sprintf(format, v...))
+	}
+	ws := c1ews.NewWorkloadSecurity(s.APIKey, s.URL).SetTracer(tracer)
+	if _, err := ws.DescribeCurrentAPIKey(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	log := strings.Join(lines, "\n")
+	if strings.Contains(log, s.APIKey) {
+		t.Errorf("API Key is not redacted:\n%s", log)
+	}
+	if !strings.Contains(log, "/apikeys/current: 200 OK") {
+		t.Errorf("status is not logged:\n%s", log)
+	}
+}
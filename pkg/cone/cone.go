@@ -35,6 +35,7 @@ type Client struct {
 	APIKey          string
 	Host            string
 	IgnoreTLSErrors bool
+	Tracer          *c1ews.Tracer
 }
 
 func NewClient(APIKey string) *Client {
@@ -50,8 +51,14 @@ func (c *Client) SetIgnoreTLSErrors(ignoreTLSErrors bool) *Client {
 	return c
 }
 
+// SetTracer - log all requests and responses
+func (c *Client) SetTracer(tracer *c1ews.Tracer) *Client {
+	c.Tracer = tracer
+	return c
+}
+
 func (c *Client) NewWorkloadSecurity(Host string) *c1ews.Client {
-	return c1ews.NewWorkloadSecurity(c.APIKey, Host)
+	return c1ews.NewWorkloadSecurity(c.APIKey, Host).SetTracer(c.Tracer)
 }
 
 type WSError struct {
@@ -122,9 +129,12 @@ func (c *Client) query(ctx context.Context,
 	if requestBody != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	transport := &http.Transport{
+	var transport http.RoundTripper = &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: c.IgnoreTLSErrors}, //nolint
 	}
+	if c.Tracer != nil {
+		transport = c.Tracer.Transport(transport)
+	}
 	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -142,7 +152,6 @@ func (c *Client) query(ctx context.Context,
 		return fmt.Errorf("code %d: %s", resp.StatusCode, wse.Message)
 	}
 	defer resp.Body.Close()
-	//io.Copy(os.Stdout, resp.Body)
 	err = json.NewDecoder(resp.Body).Decode(response)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return fmt.Errorf("response parse: %w", err)
@@ -0,0 +1,212 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  listfile.go - store lists as YAML files in directory
+//
+//////////////////////////////////////////////////////////////////////////
+
+package listfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"github.com/mpkondrashin/tmlist/pkg/process"
+	"gopkg.in/yaml.v3"
+)
+
+// IndexFileName - name of the file with all exported lists in the root of directory
+const IndexFileName = "index.yaml"
+
+const fileExt = ".yaml"
+
+// readExts - extensions of files read from directory. JSON is valid YAML,
+// so all of them are parsed the same way
+var readExts = []string{".yaml", ".yml", ".json"}
+
+var ErrDuplicateList = errors.New("duplicate list")
+
+// File - content of one list file
+type File struct {
+	Name        string   `yaml:"name"`
+	ID          int      `yaml:"id,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Items       []string `yaml:"items"`
+	// Includes - names of included lists parsed from description.
+	// Informational only: description is the source of includes
+	Includes []string `yaml:"includes,omitempty"`
+}
+
+// Index - all lists of directory
+type Index struct {
+	Kinds []KindIndex `yaml:"kinds"`
+}
+
+// KindIndex - lists of one kind
+type KindIndex struct {
+	Kind  string       `yaml:"kind"`
+	Lists []IndexEntry `yaml:"lists"`
+}
+
+// IndexEntry - one list and its file path relative to directory
+type IndexEntry struct {
+	Name string `yaml:"name"`
+	ID   int    `yaml:"id"`
+	File string `yaml:"file"`
+}
+
+// NewFile - create list file content with sorted items
+func NewFile(l *c1ews.ListResponse) *File {
+	items := append([]string{}, l.Items...)
+	sort.Strings(items)
+	return &File{
+		Name:        l.Name,
+		ID:          l.ID,
+		Description: l.Description,
+		Items:       items,
+		Includes:    process.Includes(l),
+	}
+}
+
+// ListResponse - return list with the content of the file
+func (f *File) ListResponse() c1ews.ListResponse {
+	return c1ews.ListResponse{
+		Name:        f.Name,
+		ID:          f.ID,
+		Description: f.Description,
+		Items:       append([]string{}, f.Items...),
+	}
+}
+
+// FileName - return file name for the list safe for all file systems
+func FileName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return strings.Trim(sb.String(), ".") + fileExt
+}
+
+// WriteKind - replace files of given kind in directory with given lists.
+// Return index of written files
+func WriteKind(dir string, kind *c1ews.ListKind, lists []c1ews.ListResponse) (*KindIndex, error) {
+	kindDir := filepath.Join(dir, kind.ID)
+	if err := os.MkdirAll(kindDir, 0755); err != nil {
+		return nil, err
+	}
+	stale, err := filepath.Glob(filepath.Join(kindDir, "*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	sorted := append([]c1ews.ListResponse{}, lists...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name == sorted[j].Name {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	index := &KindIndex{Kind: kind.ID}
+	used := make(map[string]bool)
+	for i := range sorted {
+		name := FileName(sorted[i].Name)
+		if used[strings.ToLower(name)] {
+			name = strings.TrimSuffix(name, fileExt) + "-" + strconv.Itoa(sorted[i].ID) + fileExt
+		}
+		used[strings.ToLower(name)] = true
+		if err := WriteFile(filepath.Join(kindDir, name), NewFile(&sorted[i])); err != nil {
+			return nil, err
+		}
+		index.Lists = append(index.Lists, IndexEntry{
+			Name: sorted[i].Name,
+			ID:   sorted[i].ID,
+			File: filepath.ToSlash(filepath.Join(kind.ID, name)),
+		})
+	}
+	return index, nil
+}
+
+// WriteFile - save list file
+func WriteFile(path string, f *File) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadFile - load list file
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Name == "" {
+		return nil, fmt.Errorf("%s: list name is missing", path)
+	}
+	return &f, nil
+}
+
+// HasKind - return true if directory has subdirectory for given kind
+func HasKind(dir string, kind *c1ews.ListKind) bool {
+	info, err := os.Stat(filepath.Join(dir, kind.ID))
+	return err == nil && info.IsDir()
+}
+
+// ReadKind - load all YAML and JSON list files of given kind from directory.
+// Missing kind directory means no lists
+func ReadKind(dir string, kind *c1ews.ListKind) ([]*File, error) {
+	var paths []string
+	for _, ext := range readExts {
+		matches, err := filepath.Glob(filepath.Join(dir, kind.ID, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	var result []*File
+	names := make(map[string]string)
+	for _, path := range paths {
+		f, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[f.Name]; ok {
+			return nil, fmt.Errorf("%s: %s: %w (%s)", path, f.Name, ErrDuplicateList, other)
+		}
+		names[f.Name] = path
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// WriteIndex - save index file to the root of directory
+func WriteIndex(dir string, index *Index) error {
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, IndexFileName), data, 0644)
+}
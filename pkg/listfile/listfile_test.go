@@ -0,0 +1,102 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  listfile_test.go - tests for lists files
+//
+//////////////////////////////////////////////////////////////////////////
+
+package listfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestFileName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"Windows", "Windows.yaml"},
+		{"SQL Server", "SQL_Server.yaml"},
+		{`C:\Temp/*`, "C__Temp__.yaml"},
+		{"..hidden", "hidden.yaml"},
+	}
+	for _, tc := range testCases {
+		actual := FileName(tc.name)
+		if actual != tc.expected {
+			t.Errorf("%s: %s instead of %s", tc.name, actual, tc.expected)
+		}
+	}
+}
+
+func TestWriteReadKind(t *testing.T) {
+	dir := t.TempDir()
+	lists := []c1ews.ListResponse{
+		{ID: 3, Name: "Database Servers", Description: "Include: Windows", Items: []string{"b", "a"}},
+		{ID: 1, Name: "Windows", Items: []string{"C:\\Windows"}},
+		{ID: 2, Name: "windows", Items: []string{"D:\\Windows"}},
+	}
+	stale := filepath.Join(dir, "dir", "Deleted.yaml")
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("name: Deleted\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := WriteKind(dir, c1ews.DirectoryLists, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, entry := range index.Lists {
+		files = append(files, entry.File)
+	}
+	expected := []string{"dir/Database_Servers.yaml", "dir/Windows.yaml", "dir/windows-2.yaml"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("%v instead of %v", files, expected)
+	}
+	read, err := ReadKind(dir, c1ews.DirectoryLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 {
+		t.Fatalf("%d files instead of 3", len(read))
+	}
+	db := read[0]
+	if !reflect.DeepEqual(db.Items, []string{"a", "b"}) {
+		t.Errorf("items are not sorted: %v", db.Items)
+	}
+	if !reflect.DeepEqual(db.Includes, []string{"Windows"}) {
+		t.Errorf("wrong includes: %v", db.Includes)
+	}
+	if db.ListResponse().Description != lists[0].Description {
+		t.Errorf("wrong description: %s", db.ListResponse().Description)
+	}
+}
+
+func TestReadKindDuplicate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.yaml": "name: Same\n",
+		"b.json": `{"name": "Same", "items": ["a"]}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, "dir", name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ReadKind(dir, c1ews.DirectoryLists); err == nil {
+		t.Errorf("duplicate list names are accepted")
+	}
+}
@@ -0,0 +1,126 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  affected.go - limit processing to lists depending on changed lists
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"sort"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+	"golang.org/x/exp/maps"
+)
+
+// references - return names of lists the list is populated from
+// directly: includes and excludes. Lists of remotes are returned in
+// qualified "<remote>/<list name>" form
+func (p *Process) references(l *c1ews.ListResponse) (result []string) {
+	if !p.Managed(l) {
+		return nil
+	}
+	names := append(p.includes(l), p.rules[l.Name].Exclude...)
+	for _, name := range names {
+		if remote, _, ok := SplitRemote(name); ok {
+			if _, found := p.remotes[remote]; found {
+				result = append(result, name)
+				continue
+			}
+		}
+		result = append(result, p.prefix+name)
+	}
+	return
+}
+
+// RemoteLists - return lists of all remotes with names in qualified
+// "<remote>/<list name>" form, sorted by name
+func (p *Process) RemoteLists() (result []c1ews.ListResponse) {
+	for _, remote := range p.remotes {
+		for _, l := range remote.in {
+			l.Name = remote.prefix + l.Name
+			result = append(result, l)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return
+}
+
+// Affected - return names of lists that can be modified because given
+// lists were changed: lists themselves, lists populated from them directly
+// or indirectly, and lists used to populate any of these lists as their
+// dependence lines can change. Changed lists of remotes are given in
+// qualified form. Result is sorted
+func (p *Process) Affected(changed []string) []string {
+	refs := make(map[string][]string)
+	dependents := make(map[string][]string)
+	add := func(owner *Process) {
+		for i := range owner.in {
+			l := &owner.in[i]
+			name := owner.prefix + l.Name
+			refs[name] = owner.references(l)
+			for _, ref := range refs[name] {
+				dependents[ref] = append(dependents[ref], name)
+			}
+		}
+	}
+	add(p)
+	for _, remote := range p.remotes {
+		add(remote)
+	}
+	populated := make(map[string]struct{})
+	queue := append([]string{}, changed...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, found := populated[name]; found {
+			continue
+		}
+		populated[name] = struct{}{}
+		queue = append(queue, dependents[name]...)
+	}
+	result := make(map[string]struct{})
+	for name := range populated {
+		result[name] = struct{}{}
+		queue = append(queue, refs[name]...)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, found := result[name]; found {
+			continue
+		}
+		result[name] = struct{}{}
+		queue = append(queue, refs[name]...)
+	}
+	for i := range p.in {
+		for _, name := range ListDependencies(&p.in[i]) {
+			if _, found := populated[name]; found {
+				result[p.in[i].Name] = struct{}{}
+				break
+			}
+		}
+	}
+	names := maps.Keys(result)
+	sort.Strings(names)
+	return names
+}
+
+// Restrict - discard computed changes of all lists except given ones.
+// Should be called after Process
+func (p *Process) Restrict(names []string) {
+	keep := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		keep[name] = struct{}{}
+	}
+	for i := range p.in {
+		if _, found := keep[p.in[i].Name]; !found {
+			p.out[i] = p.in[i]
+		}
+	}
+}
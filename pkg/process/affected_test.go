@@ -0,0 +1,101 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  affected_test.go - tests for processing limited to changed lists
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestAffected(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "Base", Items: []string{"1"}},
+		{Name: "Extra", Items: []string{"2"}},
+		{Name: "Windows", Description: "include: Base\ninclude: Extra"},
+		{Name: "Servers", Description: "include: Windows"},
+		{Name: "Other", Items: []string{"3"}},
+		{Name: "Linux", Description: "include: Other"},
+		{Name: "Old", Description: DependencePrefix + " Windows"},
+	}
+	p := NewProcess(in)
+	testCases := []struct {
+		changed  []string
+		expected []string
+	}{
+		{[]string{"Base"}, []string{"Base", "Extra", "Old", "Servers", "Windows"}},
+		{[]string{"Servers"}, []string{"Base", "Extra", "Servers", "Windows"}},
+		{[]string{"Other"}, []string{"Linux", "Other"}},
+		{nil, []string{}},
+	}
+	for _, tc := range testCases {
+		if actual := p.Affected(tc.changed); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%v: %v instead of %v", tc.changed, actual, tc.expected)
+		}
+	}
+}
+
+func TestAffectedRemote(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "Windows", Description: "include: golden/Standard"},
+		{Name: "Servers", Description: "include: Windows"},
+		{Name: "Linux", Description: "include: golden/Linux"},
+	}
+	p := NewProcess(in).SetRemote("golden", []c1ews.ListResponse{
+		{Name: "Base", Items: []string{"1"}},
+		{Name: "Standard", Description: "include: Base"},
+		{Name: "Linux", Items: []string{"2"}},
+	})
+	testCases := []struct {
+		changed  []string
+		expected []string
+	}{
+		{[]string{"golden/Standard"}, []string{"Servers", "Windows", "golden/Base", "golden/Standard"}},
+		{[]string{"golden/Base"}, []string{"Servers", "Windows", "golden/Base", "golden/Standard"}},
+		{[]string{"golden/Linux"}, []string{"Linux", "golden/Linux"}},
+	}
+	for _, tc := range testCases {
+		if actual := p.Affected(tc.changed); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%v: %v instead of %v", tc.changed, actual, tc.expected)
+		}
+	}
+	names := []string{}
+	for _, l := range p.RemoteLists() {
+		names = append(names, l.Name)
+	}
+	expected := []string{"golden/Base", "golden/Linux", "golden/Standard"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("%v is not equal to %v", names, expected)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "Base", Items: []string{"1"}},
+		{Name: "Windows", Description: "include: Base"},
+		{Name: "Other", Items: []string{"3"}},
+		{Name: "Linux", Description: "include: Other"},
+	}
+	p := NewProcess(in)
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	p.Restrict(p.Affected([]string{"Base"}))
+	var changed []string
+	_ = p.IterateChanged(func(l *c1ews.ListResponse) error {
+		changed = append(changed, l.Name)
+		return nil
+	})
+	expected := []string{"Base", "Windows"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("%v instead of %v", changed, expected)
+	}
+}
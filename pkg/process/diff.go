@@ -0,0 +1,246 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  diff.go - differences between lists before and after processing
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Diff formats
+const (
+	FormatText     = "text"
+	FormatColor    = "color"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+	colorReset = "\033[0m"
+)
+
+// ListDiff - changes of one list
+type ListDiff struct {
+	Kind              string   `json:"kind,omitempty"`
+	ID                int      `json:"id"`
+	Name              string   `json:"name"`
+	Added             []string `json:"added,omitempty"`
+	Removed           []string `json:"removed,omitempty"`
+	DescriptionBefore string   `json:"description_before,omitempty"`
+	DescriptionAfter  string   `json:"description_after,omitempty"`
+	// ItemsBefore - number of items before change
+	ItemsBefore int `json:"items_before"`
+	// ItemsAfter - number of items after change
+	ItemsAfter int `json:"items_after"`
+}
+
+// Diff - return changes between two states of the list
+func Diff(before, after *c1ews.ListResponse) ListDiff {
+	d := ListDiff{
+		ID:          after.ID,
+		Name:        after.Name,
+		Added:       Subtract(after.Items, before.Items),
+		Removed:     Subtract(before.Items, after.Items),
+		ItemsBefore: len(before.Items),
+		ItemsAfter:  len(after.Items),
+	}
+	if before.Description != after.Description {
+		d.DescriptionBefore = before.Description
+		d.DescriptionAfter = after.Description
+	}
+	return d
+}
+
+// DescriptionChanged - return true if description was changed
+func (d *ListDiff) DescriptionChanged() bool {
+	return d.DescriptionBefore != d.DescriptionAfter
+}
+
+// Diff - return changes of all lists that are going to be modified
+func (p *Process) Diff() (result []ListDiff) {
+	for i := range p.in {
+		if Equal(&p.in[i], &p.out[i]) {
+			continue
+		}
+		d := Diff(&p.in[i], &p.out[i])
+		if p.kind != nil {
+			d.Kind = p.kind.ID
+		}
+		result = append(result, d)
+	}
+	return
+}
+
+// Subtract - return items of a that are missing in b keeping order of a
+func Subtract(a, b []string) (result []string) {
+	m := make(map[string]struct{}, len(b))
+	for _, each := range b {
+		m[each] = struct{}{}
+	}
+	for _, each := range a {
+		if _, found := m[each]; !found {
+			result = append(result, each)
+		}
+	}
+	return
+}
+
+// DiffLine - one line of unified diff
+type DiffLine struct {
+	Op   byte // ' ', '-' or '+'
+	Text string
+}
+
+// DiffLines - return line by line difference between a and b
+func DiffLines(a, b []string) []DiffLine {
+	// lcs[i][j] - length of longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var result []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, DiffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{'-', a[i]})
+			i++
+		default:
+			result = append(result, DiffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, DiffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, DiffLine{'+', b[j]})
+	}
+	return result
+}
+
+// Unified - return unified diff lines of the list
+func (d *ListDiff) Unified() []DiffLine {
+	title := d.Name
+	if d.Kind != "" {
+		title = fmt.Sprintf("%s/%s", d.Kind, d.Name)
+	}
+	title = fmt.Sprintf("%s (ID %d)", title, d.ID)
+	result := []DiffLine{
+		{'-', "-- " + title},
+		{'+', "++ " + title},
+	}
+	if len(d.Added) > 0 || len(d.Removed) > 0 {
+		result = append(result, DiffLine{'@', fmt.Sprintf("@ items: %d -> %d @@", d.ItemsBefore, d.ItemsAfter)})
+		for _, item := range d.Removed {
+			result = append(result, DiffLine{'-', item})
+		}
+		for _, item := range d.Added {
+			result = append(result, DiffLine{'+', item})
+		}
+	}
+	if d.DescriptionChanged() {
+		result = append(result, DiffLine{'@', "@ description @@"})
+		result = append(result, DiffLines(
+			strings.Split(d.DescriptionBefore, "\n"),
+			strings.Split(d.DescriptionAfter, "\n"))...)
+	}
+	return result
+}
+
+// WriteDiff - output differences in given format
+func WriteDiff(w io.Writer, diffs []ListDiff, format string) error {
+	switch format {
+	case FormatText:
+		return writeUnified(w, diffs, false)
+	case FormatColor:
+		return writeUnified(w, diffs, true)
+	case FormatMarkdown:
+		return writeMarkdown(w, diffs)
+	case FormatJSON:
+		if diffs == nil {
+			diffs = []ListDiff{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diffs)
+	}
+	return fmt.Errorf("%s: %w", format, ErrUnknownFormat)
+}
+
+func writeUnified(w io.Writer, diffs []ListDiff, color bool) error {
+	for i := range diffs {
+		for _, line := range diffs[i].Unified() {
+			text := string(line.Op) + line.Text
+			if color {
+				text = colorize(line.Op, text)
+			}
+			if _, err := fmt.Fprintln(w, text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func colorize(op byte, text string) string {
+	switch op {
+	case '-':
+		return colorRed + text + colorReset
+	case '+':
+		return colorGreen + text + colorReset
+	case '@':
+		return colorCyan + text + colorReset
+	}
+	return text
+}
+
+func writeMarkdown(w io.Writer, diffs []ListDiff) error {
+	for i := range diffs {
+		d := &diffs[i]
+		_, err := fmt.Fprintf(w, "### %s\n\nAdded: %d, removed: %d, items: %d → %d\n\n```diff\n",
+			d.Name, len(d.Added), len(d.Removed), d.ItemsBefore, d.ItemsAfter)
+		if err != nil {
+			return err
+		}
+		if err := writeUnified(w, diffs[i:i+1], false); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, "```\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
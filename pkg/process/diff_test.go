@@ -0,0 +1,93 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  diff_test.go - tests for functions in diff.go
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestDiff(t *testing.T) {
+	before := &c1ews.ListResponse{
+		ID:          1,
+		Name:        "nameA",
+		Description: "desc A\ninclude: nameB",
+		Items:       []string{"1", "2", "3"},
+	}
+	after := &c1ews.ListResponse{
+		ID:          1,
+		Name:        "nameA",
+		Description: "desc A\ninclude: nameB",
+		Items:       []string{"2", "3", "4"},
+	}
+	d := Diff(before, after)
+	if !reflect.DeepEqual(d.Added, []string{"4"}) {
+		t.Errorf("Added: %v", d.Added)
+	}
+	if !reflect.DeepEqual(d.Removed, []string{"1"}) {
+		t.Errorf("Removed: %v", d.Removed)
+	}
+	if d.DescriptionChanged() {
+		t.Errorf("Description is not changed")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"a", "b", "c"}
+	b := []string{"a", "c", "d"}
+	actual := DiffLines(a, b)
+	expected := []DiffLine{{' ', "a"}, {'-', "b"}, {' ', "c"}, {'+', "d"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+}
+
+func TestProcessDiff(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{ID: 1, Name: "nameA", Description: "desc A", Items: []string{"1", "2"}},
+		{ID: 2, Name: "nameB", Description: "desc B\ninclude: nameA", Items: []string{"manual"}},
+	}
+	p := NewProcess(in)
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	diffs := p.Diff()
+	if len(diffs) != 2 {
+		t.Fatalf("%d diffs instead of 2", len(diffs))
+	}
+	var text bytes.Buffer
+	if err := WriteDiff(&text, diffs, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"--- nameB (ID 2)", "-manual", "+1", "+" + DependencePrefix + " nameB"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("%q not found in\n%s", expected, text.String())
+		}
+	}
+	var data bytes.Buffer
+	if err := WriteDiff(&data, diffs, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ListDiff
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, diffs) {
+		t.Errorf("%v is not equal to %v", decoded, diffs)
+	}
+	if err := WriteDiff(&data, diffs, "xml"); err == nil {
+		t.Errorf("unknown format accepted")
+	}
+}
@@ -0,0 +1,61 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  limits.go - safety thresholds on amount of changes
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrLimitExceeded = errors.New("safety limit exceeded")
+
+// Limits - maximum allowed amount of changes. Zero value means no limit
+type Limits struct {
+	// MaxRemovedItems - maximum number of items removed from one list
+	MaxRemovedItems int
+	// MaxShrinkPercent - maximum decrease of number of items of one list in percents
+	MaxShrinkPercent int
+	// MaxModifiedLists - maximum number of modified lists
+	MaxModifiedLists int
+}
+
+// Check - return error describing all exceeded limits
+func (l *Limits) Check(diffs []ListDiff) error {
+	var violations []string
+	if l.MaxModifiedLists > 0 && len(diffs) > l.MaxModifiedLists {
+		violations = append(violations, fmt.Sprintf("%d lists to be modified (maximum %d)",
+			len(diffs), l.MaxModifiedLists))
+	}
+	for i := range diffs {
+		d := &diffs[i]
+		if l.MaxRemovedItems > 0 && len(d.Removed) > l.MaxRemovedItems {
+			violations = append(violations, fmt.Sprintf("%s: %d items to be removed (maximum %d)",
+				d.Name, len(d.Removed), l.MaxRemovedItems))
+		}
+		shrink := d.ShrinkPercent()
+		if l.MaxShrinkPercent > 0 && shrink > l.MaxShrinkPercent {
+			violations = append(violations, fmt.Sprintf("%s: shrinks by %d%% (maximum %d%%)",
+				d.Name, shrink, l.MaxShrinkPercent))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrLimitExceeded, strings.Join(violations, "; "))
+}
+
+// ShrinkPercent - return decrease of number of items in percents
+func (d *ListDiff) ShrinkPercent() int {
+	if d.ItemsBefore == 0 || d.ItemsAfter >= d.ItemsBefore {
+		return 0
+	}
+	return (d.ItemsBefore - d.ItemsAfter) * 100 / d.ItemsBefore
+}
@@ -0,0 +1,43 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  limits_test.go - tests for functions in limits.go
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	diffs := []ListDiff{
+		{Name: "nameA", Removed: []string{"1", "2", "3"}, ItemsBefore: 4, ItemsAfter: 1},
+		{Name: "nameB", Added: []string{"1"}, ItemsBefore: 1, ItemsAfter: 2},
+	}
+	tests := []struct {
+		name     string
+		limits   Limits
+		exceeded bool
+	}{
+		{"no limits", Limits{}, false},
+		{"removed ok", Limits{MaxRemovedItems: 3}, false},
+		{"removed", Limits{MaxRemovedItems: 2}, true},
+		{"shrink ok", Limits{MaxShrinkPercent: 75}, false},
+		{"shrink", Limits{MaxShrinkPercent: 50}, true},
+		{"lists ok", Limits{MaxModifiedLists: 2}, false},
+		{"lists", Limits{MaxModifiedLists: 1}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.limits.Check(diffs)
+			if errors.Is(err, ErrLimitExceeded) != test.exceeded {
+				t.Errorf("unexpected result: %v", err)
+			}
+		})
+	}
+}
@@ -10,10 +10,15 @@
 package process
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mpkondrashin/tmlist/pkg/c1ews"
 	"golang.org/x/exp/maps"
@@ -27,21 +32,103 @@ func Equal(a, b *c1ews.ListResponse) bool {
 
 // Includes - list all includes of the list
 func Includes(l *c1ews.ListResponse) (result []string) {
+	return Values(l, "include")
+}
+
+// Values - return values of all "key: value" description lines with given key.
+// Key is case insensitive
+func Values(l *c1ews.ListResponse, key string) (result []string) {
 	for _, line := range strings.Split(l.Description, "\n") {
 		colon := strings.Index(line, ":")
 		if colon == -1 {
 			continue
 		}
-		include := line[:colon]
-		if strings.ToLower(strings.TrimSpace(include)) != "include" {
+		k := line[:colon]
+		if !strings.EqualFold(strings.TrimSpace(k), key) {
 			continue
 		}
-		name := strings.TrimSpace(line[colon+1:])
-		result = append(result, name)
+		value := strings.TrimSpace(line[colon+1:])
+		result = append(result, value)
 	}
 	return
 }
 
+// ManagedBy - return name of TMList instance from ownership marker or empty string
+func ManagedBy(l *c1ews.ListResponse) string {
+	for _, value := range Values(l, ManagedByKey) {
+		if strings.HasPrefix(value, ManagedByPrefix) {
+			return strings.TrimPrefix(value, ManagedByPrefix)
+		}
+	}
+	return ""
+}
+
+// SetManagedBy - add ownership marker or replace existing one
+func SetManagedBy(l *c1ews.ListResponse, instance string) {
+	SetValue(l, ManagedByKey, ManagedByPrefix+instance)
+}
+
+// SetValue - replace first "key: value" description line with given key
+// or add new line if there is no such line
+func SetValue(l *c1ews.ListResponse, key, value string) {
+	line := fmt.Sprintf("%s: %s", key, value)
+	lines := strings.Split(l.Description, "\n")
+	for i := range lines {
+		colon := strings.Index(lines[i], ":")
+		if colon != -1 && strings.EqualFold(strings.TrimSpace(lines[i][:colon]), key) {
+			lines[i] = line
+			l.Description = strings.Join(lines, "\n")
+			return
+		}
+	}
+	if l.Description == "" {
+		l.Description = line
+		return
+	}
+	l.Description += "\n" + line
+}
+
+// ClearValues - remove all "key: value" description lines with given key
+func ClearValues(l *c1ews.ListResponse, key string) {
+	result := []string{}
+	for _, line := range strings.Split(l.Description, "\n") {
+		colon := strings.Index(line, ":")
+		if colon != -1 && strings.EqualFold(strings.TrimSpace(line[:colon]), key) {
+			continue
+		}
+		result = append(result, line)
+	}
+	l.Description = strings.Join(result, "\n")
+}
+
+// ItemsHash - return hash of items not depending on their order
+func ItemsHash(items []string) string {
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// StoredHash - return items hash saved in description or empty string
+func StoredHash(l *c1ews.ListResponse) string {
+	values := Values(l, HashKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// SetItemsHash - save hash of current items to description
+func SetItemsHash(l *c1ews.ListResponse) {
+	SetValue(l, HashKey, ItemsHash(l.Items))
+}
+
+// ManuallyEdited - return true if items differ from ones saved by last TMList run
+func ManuallyEdited(l *c1ews.ListResponse) bool {
+	stored := StoredHash(l)
+	return stored != "" && stored != ItemsHash(l.Items)
+}
+
 // HasIncludes - return true if list has includes
 func HasIncludes(l *c1ews.ListResponse) bool {
 	return len(Includes(l)) > 0
@@ -99,6 +186,86 @@ func AddDependences(l *c1ews.ListResponse, name ...string) {
 	l.Description = l.Description + dependence
 }
 
+// SyncMetadata - information about last TMList modification of generated list
+type SyncMetadata struct {
+	Synced  time.Time
+	Version string
+	Count   int
+	Sources []string
+}
+
+// ReadSyncMetadata - return sync metadata from description or nil if there is none
+func ReadSyncMetadata(l *c1ews.ListResponse) *SyncMetadata {
+	synced := Values(l, SyncedKey)
+	if len(synced) == 0 {
+		return nil
+	}
+	result := &SyncMetadata{}
+	result.Synced, _ = time.Parse(time.RFC3339, synced[0])
+	if version := Values(l, VersionKey); len(version) > 0 {
+		result.Version = version[0]
+	}
+	if count := Values(l, CountKey); len(count) > 0 {
+		result.Count, _ = strconv.Atoi(count[0])
+	}
+	if sources := Values(l, SourcesKey); len(sources) > 0 && sources[0] != "" {
+		for _, name := range strings.Split(sources[0], ",") {
+			result.Sources = append(result.Sources, strings.TrimSpace(name))
+		}
+	}
+	return result
+}
+
+// ClearSyncMetadata - remove sync metadata lines from description
+func ClearSyncMetadata(l *c1ews.ListResponse) {
+	result := []string{}
+	for _, line := range strings.Split(l.Description, "\n") {
+		if isSyncMetadata(line) {
+			continue
+		}
+		result = append(result, line)
+	}
+	l.Description = strings.Join(result, "\n")
+}
+
+// SetSyncMetadata - replace sync metadata with new one. Metadata lines are
+// placed as one block at the end of description
+func SetSyncMetadata(l *c1ews.ListResponse, meta *SyncMetadata) {
+	ClearSyncMetadata(l)
+	block := []string{
+		fmt.Sprintf("%s: %s", SyncedKey, meta.Synced.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("%s: %s", VersionKey, meta.Version),
+		fmt.Sprintf("%s: %d", CountKey, meta.Count),
+		fmt.Sprintf("%s: %s", SourcesKey, strings.Join(meta.Sources, ", ")),
+	}
+	if l.Description != "" {
+		l.Description += "\n"
+	}
+	l.Description += strings.Join(block, "\n")
+}
+
+func isSyncMetadata(line string) bool {
+	colon := strings.Index(line, ":")
+	if colon == -1 {
+		return false
+	}
+	key := strings.TrimSpace(line[:colon])
+	for _, each := range []string{SyncedKey, VersionKey, CountKey, SourcesKey} {
+		if strings.EqualFold(key, each) {
+			return true
+		}
+	}
+	return false
+}
+
+// EqualIgnoringSyncMetadata - return true if lists differ only by sync metadata
+func EqualIgnoringSyncMetadata(a, b *c1ews.ListResponse) bool {
+	ac, bc := *a, *b
+	ClearSyncMetadata(&ac)
+	ClearSyncMetadata(&bc)
+	return Equal(&ac, &bc)
+}
+
 // RemoveDuplicates - remove duplicates from string slice. Return in sorted order
 func RemoveDuplicates(names []string) (result []string) {
 	m := make(map[string]struct{})
@@ -118,3 +285,10 @@ func ListFromResponse(response *c1ews.ListResponse) *c1ews.List {
 		Items:       response.Items,
 	}
 }
+
+// Fingerprint - return hash of list fields that could be changed by TMList or console users
+func Fingerprint(l *c1ews.ListResponse) string {
+	data, _ := json.Marshal(ListFromResponse(l))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -296,3 +296,34 @@ func TestListFromResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestManagedBy(t *testing.T) {
+	a := &c1ews.ListResponse{
+		Name:        "nameA",
+		Description: "desc A\ninclude: dddX",
+	}
+	if actual := ManagedBy(a); actual != "" {
+		t.Errorf("unexpected owner %s", actual)
+	}
+	SetManagedBy(a, "team1")
+	if actual := ManagedBy(a); actual != "team1" {
+		t.Errorf("owner %s instead of team1", actual)
+	}
+	SetManagedBy(a, "team2")
+	expected := "desc A\ninclude: dddX\nManaged-By: tmlist/team2"
+	if a.Description != expected {
+		t.Errorf("[%v] is not equal to [%v] after SetManagedBy", a.Description, expected)
+	}
+}
+
+func TestClearValues(t *testing.T) {
+	a := &c1ews.ListResponse{
+		Name:        "nameA",
+		Description: "desc A\nmanaged-by: tmlist/team1\ninclude: dddX\nManaged-By: tmlist/team2",
+	}
+	ClearValues(a, ManagedByKey)
+	expected := "desc A\ninclude: dddX"
+	if a.Description != expected {
+		t.Errorf("[%v] is not equal to [%v] after ClearValues", a.Description, expected)
+	}
+}
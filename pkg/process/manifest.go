@@ -0,0 +1,106 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  manifest.go - composition rules kept outside of lists descriptions
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest modes - how manifest rules are combined with description includes
+const (
+	// ManifestMerge - use both manifest rules and "Include:" description lines
+	ManifestMerge = "merge"
+	// ManifestReplace - ignore "Include:" description lines
+	ManifestReplace = "replace"
+)
+
+var ErrDuplicateRule = errors.New("duplicate manifest rule")
+
+// Rule - composition of one list
+type Rule struct {
+	// Kind - kind ID of the list (dir, ext, file, ip, mac or port)
+	Kind string `yaml:"kind" mapstructure:"kind"`
+	// List - name of the populated list
+	List string `yaml:"list" mapstructure:"list"`
+	// Include - names of lists which items are added
+	Include []string `yaml:"include" mapstructure:"include"`
+	// Exclude - names of lists which items are removed
+	Exclude []string `yaml:"exclude" mapstructure:"exclude"`
+	// Items - extra items to add
+	Items []string `yaml:"items" mapstructure:"items"`
+}
+
+// Manifest - composition rules of lists
+type Manifest struct {
+	Rules []Rule `yaml:"manifest" mapstructure:"manifest"`
+}
+
+// LoadManifest - read manifest from YAML file with "manifest" section
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// ForKind - return rules for lists of given kind
+func (m *Manifest) ForKind(kind string) (result []Rule) {
+	if m == nil {
+		return nil
+	}
+	for _, rule := range m.Rules {
+		if rule.Kind == kind {
+			result = append(result, rule)
+		}
+	}
+	return
+}
+
+// SetManifest - use given rules to populate lists. Mode defines whether
+// "Include:" description lines are still used. Empty mode means merge
+func (p *Process) SetManifest(rules []Rule, mode string) error {
+	switch mode {
+	case "", ManifestMerge, ManifestReplace:
+	default:
+		return fmt.Errorf("%s: unknown manifest mode", mode)
+	}
+	p.rules = make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		if _, found := p.rules[rule.List]; found {
+			return fmt.Errorf("%s: %w", rule.List, ErrDuplicateRule)
+		}
+		p.rules[rule.List] = rule
+	}
+	p.manifestMode = mode
+	return nil
+}
+
+// checkRules - return error if manifest rule refers to missing list
+func (p *Process) checkRules() error {
+	names := maps.Keys(p.rules)
+	sort.Strings(names)
+	for _, name := range names {
+		if p.FindList(name) == nil {
+			return fmt.Errorf("manifest: %w", p.ListNotFoundError(name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  manifest_test.go - tests for manifest rules
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func manifestLists() []c1ews.ListResponse {
+	return []c1ews.ListResponse{
+		{Name: "nameA", Items: []string{"1", "2", "3"}},
+		{Name: "nameB", Items: []string{"4"}},
+		{Name: "nameC", Items: []string{"2"}},
+		{Name: "target", Description: "include: nameB", Items: []string{"old"}},
+	}
+}
+
+func TestManifest(t *testing.T) {
+	rules := []Rule{{
+		List:    "target",
+		Include: []string{"nameA"},
+		Exclude: []string{"nameC"},
+		Items:   []string{"5"},
+	}}
+	testCases := []struct {
+		mode     string
+		expected []string
+	}{
+		{ManifestMerge, []string{"1", "3", "4", "5"}},
+		{ManifestReplace, []string{"1", "3", "5"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			p := NewProcess(manifestLists())
+			if err := p.SetManifest(rules, tc.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Process(); err != nil {
+				t.Fatal(err)
+			}
+			actual := p.FindList("target").Items
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("%v instead of %v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []Rule
+		err   error
+	}{
+		{"missing target", []Rule{{List: "missing", Include: []string{"nameA"}}}, ErrListNotFound},
+		{"missing include", []Rule{{List: "target", Include: []string{"missing"}}}, ErrListNotFound},
+		{"missing exclude", []Rule{{List: "target", Exclude: []string{"missing"}}}, ErrListNotFound},
+		{"cycle", []Rule{
+			{List: "target", Include: []string{"nameA"}},
+			{List: "nameA", Exclude: []string{"target"}},
+		}, ErrCycleDependence},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewProcess(manifestLists())
+			if err := p.SetManifest(tc.rules, ManifestMerge); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Process(); !errors.Is(err, tc.err) {
+				t.Errorf("error %v instead of %v", err, tc.err)
+			}
+		})
+	}
+	p := NewProcess(manifestLists())
+	err := p.SetManifest([]Rule{{List: "target"}, {List: "target"}}, ManifestMerge)
+	if !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("error %v instead of %v", err, ErrDuplicateRule)
+	}
+	if err := p.SetManifest(nil, "wrong"); err == nil {
+		t.Errorf("unknown mode is accepted")
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	data := `manifest:
+  - kind: dir
+    list: Database Servers
+    include: [Windows, SQL Server]
+  - kind: ext
+    list: Logs
+    items: [log]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := m.ForKind("dir")
+	if len(rules) != 1 || rules[0].List != "Database Servers" ||
+		!reflect.DeepEqual(rules[0].Include, []string{"Windows", "SQL Server"}) {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+}
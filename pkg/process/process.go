@@ -12,6 +12,7 @@ package process
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mpkondrashin/tmlist/pkg/c1ews"
 	"github.com/mpkondrashin/tmlist/pkg/levenshtein"
@@ -20,35 +21,200 @@ import (
 
 const (
 	DependencePrefix = "Do not delete this list! It is used to populate the following lists:"
+	ManagedByKey     = "Managed-By"
+	ManagedByPrefix  = "tmlist/"
+	HashKey          = "TMList-Hash"
+	SyncedKey        = "TMList-Synced"
+	VersionKey       = "TMList-Version"
+	CountKey         = "TMList-Count"
+	SourcesKey       = "TMList-Sources"
+)
+
+// Policies for lists with items added or removed not by TMList
+const (
+	// ManualEditWarn - report and do not modify the list
+	ManualEditWarn = "warn"
+	// ManualEditPreserve - move manually added items to inbox list
+	ManualEditPreserve = "preserve"
+	// ManualEditOverwrite - replace items as usual
+	ManualEditOverwrite = "overwrite"
 )
 
 var (
 	ErrCycleDependence = errors.New("cycle dependence")
 	ErrListNotFound    = errors.New("not found")
+	ErrUnknownPolicy   = errors.New("unknown policy")
 )
 
+// ManualEdit - list edited not by TMList since last run
+type ManualEdit struct {
+	Name string `json:"name"`
+	// Items - items added manually. TMList is going to remove them
+	Items []string `json:"items"`
+	// Removed - generated items removed manually. TMList is going to add them back
+	Removed []string `json:"removed,omitempty"`
+}
+
 type Process struct {
-	in  []c1ews.ListResponse
-	out []c1ews.ListResponse
+	kind       *c1ews.ListKind
+	instance   string
+	manualEdit string
+	inbox      string
+	edits      []ManualEdit
+	// frozen - names of manually edited lists kept as is (warn policy)
+	frozen  map[string]bool
+	version string
+	now     func() time.Time
+	sources map[string][]string
+	in      []c1ews.ListResponse
+	out     []c1ews.ListResponse
+
+	// rules - manifest rules by list name
+	rules        map[string]Rule
+	manifestMode string
+
+	// remotes - lists of other managers by remote name
+	remotes map[string]*Process
+	// prefix - remote name with separator for lists of remote, empty for own lists
+	prefix string
 }
 
 func NewProcess(in []c1ews.ListResponse) *Process {
 	p := &Process{
-		in: in,
+		in:  in,
+		now: time.Now,
 	}
-	p.populateOut()
 	return p
 }
 
+// SetKind - set kind of processed lists to use its merge and validation rules
+func (p *Process) SetKind(kind *c1ews.ListKind) *Process {
+	p.kind = kind
+	return p
+}
+
+// SetInstance - process only lists with ownership marker of given
+// TMList instance. Empty name means all lists are processed
+func (p *Process) SetInstance(instance string) *Process {
+	p.instance = instance
+	return p
+}
+
+// SetVersion - set TMList version to be saved in sync metadata
+func (p *Process) SetVersion(version string) *Process {
+	p.version = version
+	return p
+}
+
+// SetManualEditPolicy - set action for generated lists edited not by TMList.
+// Inbox is name of the list to keep manually added items for preserve policy.
+// Empty policy means overwrite
+func (p *Process) SetManualEditPolicy(policy string, inbox string) error {
+	switch policy {
+	case "", ManualEditWarn, ManualEditOverwrite:
+	case ManualEditPreserve:
+		if inbox == "" {
+			return fmt.Errorf("%s policy requires inbox list", policy)
+		}
+	default:
+		return fmt.Errorf("%s: %w", policy, ErrUnknownPolicy)
+	}
+	p.manualEdit = policy
+	p.inbox = inbox
+	return nil
+}
+
+// ManualEdits - return generated lists that were edited not by TMList
+func (p *Process) ManualEdits() []ManualEdit {
+	return p.edits
+}
+
+// Managed - return true if list can be modified by this instance
+func (p *Process) Managed(l *c1ews.ListResponse) bool {
+	return p.instance == "" || ManagedBy(l) == p.instance
+}
+
+// Unmarked - return names of lists with includes but without ownership
+// marker. Such lists are not processed if instance is set
+func (p *Process) Unmarked() (result []string) {
+	if p.instance == "" {
+		return
+	}
+	for i := range p.in {
+		_, hasRule := p.rules[p.in[i].Name]
+		if (HasIncludes(&p.in[i]) || hasRule) && ManagedBy(&p.in[i]) == "" {
+			result = append(result, p.in[i].Name)
+		}
+	}
+	return
+}
+
+// includes - return includes of the list from description and manifest
+// if it is managed by this instance
+func (p *Process) includes(l *c1ews.ListResponse) (result []string) {
+	if !p.Managed(l) {
+		return nil
+	}
+	if p.manifestMode != ManifestReplace {
+		result = Includes(l)
+	}
+	return append(result, p.rules[l.Name].Include...)
+}
+
+// generated - return true if items of the list are computed by TMList.
+// Frozen lists keep their current items
+func (p *Process) generated(l *c1ews.ListResponse) bool {
+	if !p.Managed(l) || p.frozen[l.Name] {
+		return false
+	}
+	_, hasRule := p.rules[l.Name]
+	return hasRule || len(p.includes(l)) > 0
+}
+
 func (p *Process) populateOut() {
 	p.out = make([]c1ews.ListResponse, len(p.in))
 	copy(p.out, p.in)
 	for i := range p.in {
-		Cleanup(&p.out[i])
+		if p.generated(&p.out[i]) {
+			p.out[i].Items = []string{}
+		}
 	}
 }
 
+// Process - compute items of generated lists. Output lists are populated
+// here, when manifest rules deciding which lists are generated are set
 func (p *Process) Process() error {
+	p.frozen = nil
+	p.populateOut()
+	if err := p.checkRules(); err != nil {
+		return err
+	}
+	if err := p.compute(); err != nil {
+		return err
+	}
+	p.findManualEdits()
+	if p.manualEdit == ManualEditWarn && len(p.edits) > 0 {
+		// Lists including frozen ones are computed again from their
+		// current content, so they are consistent with what is kept
+		p.frozen = make(map[string]bool)
+		for _, edit := range p.edits {
+			p.frozen[edit.Name] = true
+		}
+		p.populateOut()
+		if err := p.compute(); err != nil {
+			return err
+		}
+	}
+	if err := p.handleManualEdits(); err != nil {
+		return err
+	}
+	p.updateSyncMetadata()
+	return p.validate()
+}
+
+// compute - add items of included lists to populated generated lists
+func (p *Process) compute() error {
+	p.sources = make(map[string][]string)
 	for n := range p.out {
 		if err := p.GetAllItems(&p.out[n]); err != nil {
 			return err
@@ -57,34 +223,140 @@ func (p *Process) Process() error {
 	return nil
 }
 
+// updateSyncMetadata - set sync metadata of changed generated lists and keep
+// description of others intact
+func (p *Process) updateSyncMetadata() {
+	now := p.now()
+	for i := range p.out {
+		if !p.generated(&p.in[i]) {
+			continue
+		}
+		if EqualIgnoringSyncMetadata(&p.in[i], &p.out[i]) {
+			p.out[i].Description = p.in[i].Description
+			continue
+		}
+		SetSyncMetadata(&p.out[i], &SyncMetadata{
+			Synced:  now,
+			Version: p.version,
+			Count:   len(p.out[i].Items),
+			Sources: p.sources[p.out[i].Name],
+		})
+	}
+}
+
+// findManualEdits - find generated lists which items do not match previously
+// saved hash and compare them with computed items
+func (p *Process) findManualEdits() {
+	p.edits = nil
+	for i := range p.out {
+		if !p.generated(&p.in[i]) || !ManuallyEdited(&p.in[i]) {
+			continue
+		}
+		p.edits = append(p.edits, ManualEdit{
+			Name:    p.in[i].Name,
+			Items:   Subtract(p.in[i].Items, p.out[i].Items),
+			Removed: Subtract(p.out[i].Items, p.in[i].Items),
+		})
+	}
+}
+
+// handleManualEdits - save hash of generated items and apply manual edit
+// policy to lists found by findManualEdits
+func (p *Process) handleManualEdits() error {
+	for i := range p.out {
+		if p.frozen[p.in[i].Name] {
+			p.out[i] = p.in[i]
+			continue
+		}
+		if p.generated(&p.in[i]) {
+			SetItemsHash(&p.out[i])
+		}
+	}
+	if p.manualEdit != ManualEditPreserve {
+		return nil
+	}
+	for _, edit := range p.edits {
+		inbox, err := p.FindListWithError(p.inbox)
+		if err != nil {
+			return fmt.Errorf("inbox list: %w", err)
+		}
+		AddToTheList(inbox, edit.Items)
+	}
+	return nil
+}
+
+// validate - check items of all lists that are going to be changed
+func (p *Process) validate() error {
+	if p.kind == nil || p.kind.Validate == nil {
+		return nil
+	}
+	return p.IterateChanged(func(l *c1ews.ListResponse) error {
+		for _, item := range l.Items {
+			if err := p.kind.Validate(item); err != nil {
+				return fmt.Errorf("list %s: %w", l.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (p *Process) GetAllItemsWithMap(l *c1ews.ListResponse, seen map[string]struct{}) error {
-	AddDependences(l, maps.Keys(seen)...)
+	if p.Managed(l) {
+		AddDependences(l, maps.Keys(seen)...)
+	}
 	/*	if len(l.Items) > 0 {
 		return nil
 	}*/
-	includes := Includes(l)
-	if len(includes) == 0 {
+	if !p.generated(l) {
 		return nil
 	}
-	seen[l.Name] = struct{}{}
-	for _, name := range includes {
-		list, err := p.FindListWithError(name)
+	seen[p.prefix+l.Name] = struct{}{}
+	for _, name := range p.includes(l) {
+		owner, list, err := p.resolve(name)
 		if err != nil {
 			return fmt.Errorf("included in %s: %w", l.Name, err)
 		}
-		_, found := seen[list.Name]
+		_, found := seen[owner.prefix+list.Name]
 		if found {
-			return fmt.Errorf("list %s refers to %s: %w", l.Name, list.Name, ErrCycleDependence)
+			return fmt.Errorf("list %s refers to %s: %w", p.prefix+l.Name, owner.prefix+list.Name, ErrCycleDependence)
 		}
-		if err := p.GetAllItemsWithMap(list, seen); err != nil {
+		if err := owner.GetAllItemsWithMap(list, seen); err != nil {
 			return err
 		}
 		AddToTheList(l, list.Items)
+		p.addSources(l.Name, owner.prefix+list.Name)
+		p.addSources(l.Name, owner.sources[list.Name]...)
+	}
+	rule := p.rules[l.Name]
+	if len(rule.Items) > 0 {
+		AddToTheList(l, rule.Items)
 	}
-	delete(seen, l.Name)
+	for _, name := range rule.Exclude {
+		owner, list, err := p.resolve(name)
+		if err != nil {
+			return fmt.Errorf("excluded from %s: %w", l.Name, err)
+		}
+		_, found := seen[owner.prefix+list.Name]
+		if found {
+			return fmt.Errorf("list %s refers to %s: %w", l.Name, owner.prefix+list.Name, ErrCycleDependence)
+		}
+		if err := owner.GetAllItemsWithMap(list, seen); err != nil {
+			return err
+		}
+		l.Items = append([]string{}, Subtract(l.Items, list.Items)...)
+	}
+	delete(seen, p.prefix+l.Name)
 	return nil
 }
 
+// addSources - remember lists used to populate given list
+func (p *Process) addSources(name string, sources ...string) {
+	if p.sources == nil || len(sources) == 0 {
+		return
+	}
+	p.sources[name] = RemoveDuplicates(append(p.sources[name], sources...))
+}
+
 func (p *Process) GetAllItems(l *c1ews.ListResponse) error {
 	return p.GetAllItemsWithMap(l, make(map[string]struct{}))
 }
@@ -120,6 +392,11 @@ func (p *Process) ListNotFoundError(name string) error {
 	return fmt.Errorf("%s: %w (did you mean \"%s\"?)", name, ErrListNotFound, closest)
 }
 
+// Observed - return lists in the state they were before processing
+func (p *Process) Observed() []c1ews.ListResponse {
+	return p.in
+}
+
 func (p *Process) IterateChanged(callback func(*c1ews.ListResponse) error) error {
 	for i := range p.in {
 		if !Equal(&p.in[i], &p.out[i]) {
@@ -14,6 +14,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mpkondrashin/tmlist/pkg/c1ews"
 )
@@ -179,3 +180,155 @@ func TestLoop(t *testing.T) {
 		})
 	}
 }
+
+func TestInstance(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "nameA",
+			Description: "desc A",
+			Items:       []string{"1", "2"},
+		},
+		{Name: "nameB",
+			Description: "desc B\ninclude: nameA\nManaged-By: tmlist/team1",
+			Items:       []string{"3"},
+		},
+		{Name: "nameC",
+			Description: "desc C\ninclude: nameA\nManaged-By: tmlist/team2",
+			Items:       []string{"4"},
+		},
+		{Name: "nameD",
+			Description: "desc D\ninclude: nameA",
+			Items:       []string{"5"},
+		},
+	}
+	p := NewProcess(in).SetInstance("team1")
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	_ = p.IterateChanged(func(l *c1ews.ListResponse) error {
+		changed = append(changed, l.Name)
+		return nil
+	})
+	if !reflect.DeepEqual(changed, []string{"nameB"}) {
+		t.Errorf("changed lists: %v", changed)
+	}
+	if unmarked := p.Unmarked(); !reflect.DeepEqual(unmarked, []string{"nameD"}) {
+		t.Errorf("unmarked lists: %v", unmarked)
+	}
+}
+
+func TestManualEdits(t *testing.T) {
+	generated := c1ews.ListResponse{
+		Name:        "nameB",
+		Description: "desc B\ninclude: nameA\n" + DependencePrefix + " nameC",
+		Items:       []string{"1", "2", "3"},
+	}
+	SetItemsHash(&generated)
+	generated.Items = []string{"1", "2", "manual"}
+	in := []c1ews.ListResponse{
+		{Name: "nameA",
+			Description: "desc A\n" + DependencePrefix + " nameB, nameC",
+			Items:       []string{"1", "2", "3"},
+		},
+		generated,
+		{Name: "nameC",
+			Description: "desc C\ninclude: nameB",
+			Items:       []string{},
+		},
+		{Name: "inbox",
+			Description: "manual items",
+			Items:       []string{},
+		},
+	}
+	tests := []struct {
+		policy string
+		items  []string
+		inbox  []string
+	}{
+		{ManualEditWarn, []string{"1", "2", "manual"}, []string{}},
+		{ManualEditPreserve, []string{"1", "2", "3"}, []string{"manual"}},
+		{ManualEditOverwrite, []string{"1", "2", "3"}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			p := NewProcess(in)
+			if err := p.SetManualEditPolicy(test.policy, "inbox"); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Process(); err != nil {
+				t.Fatal(err)
+			}
+			edits := p.ManualEdits()
+			expected := []ManualEdit{{Name: "nameB", Items: []string{"manual"}, Removed: []string{"3"}}}
+			if !reflect.DeepEqual(edits, expected) {
+				t.Errorf("%v is not equal to %v", edits, expected)
+			}
+			if actual := p.FindList("nameB").Items; !reflect.DeepEqual(actual, test.items) {
+				t.Errorf("nameB items %v instead of %v", actual, test.items)
+			}
+			if actual := p.FindList("nameC").Items; !reflect.DeepEqual(actual, test.items) {
+				t.Errorf("including list nameC items %v instead of %v", actual, test.items)
+			}
+			if actual := p.FindList("inbox").Items; !reflect.DeepEqual(actual, test.inbox) {
+				t.Errorf("inbox items %v instead of %v", actual, test.inbox)
+			}
+		})
+	}
+	if err := NewProcess(in).SetManualEditPolicy("ignore", ""); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestItemsHashIsStable(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "nameA", Description: "desc A", Items: []string{"1", "2"}},
+		{Name: "nameB", Description: "desc B\ninclude: nameA", Items: []string{}},
+	}
+	p := NewProcess(in)
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	var out []c1ews.ListResponse
+	_ = p.IterateChanged(func(l *c1ews.ListResponse) error {
+		out = append(out, *l)
+		return nil
+	})
+	p = NewProcess(out)
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	_ = p.IterateChanged(func(l *c1ews.ListResponse) error {
+		t.Errorf("%s changed on second run", l.Name)
+		return nil
+	})
+	if len(p.ManualEdits()) != 0 {
+		t.Errorf("unexpected manual edits: %v", p.ManualEdits())
+	}
+}
+
+func TestSyncMetadata(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "nameA", Description: "desc A", Items: []string{"1", "2"}},
+		{Name: "nameB", Description: "desc B\ninclude: nameA", Items: []string{"3"}},
+		{Name: "nameC", Description: "desc C\ninclude: nameB", Items: []string{}},
+	}
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	p := NewProcess(in).SetVersion("v1.2.3")
+	p.now = func() time.Time { return now }
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	if meta := ReadSyncMetadata(p.FindList("nameA")); meta != nil {
+		t.Errorf("metadata is set for list without includes: %v", meta)
+	}
+	actual := ReadSyncMetadata(p.FindList("nameC"))
+	expected := &SyncMetadata{
+		Synced:  now,
+		Version: "v1.2.3",
+		Count:   2,
+		Sources: []string{"nameA", "nameB"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v is not equal to %v", actual, expected)
+	}
+}
@@ -0,0 +1,63 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  remote.go - include lists of other managers
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+// RemoteSeparator - separates remote name from list name in qualified
+// include: "Include: golden/Windows"
+const RemoteSeparator = "/"
+
+// SetRemote - add lists of other manager that can be included using
+// "<remote>/<list name>" form. Remote lists are never modified
+func (p *Process) SetRemote(name string, lists []c1ews.ListResponse) *Process {
+	if p.remotes == nil {
+		p.remotes = make(map[string]*Process)
+	}
+	remote := NewProcess(lists)
+	remote.prefix = name + RemoteSeparator
+	remote.remotes = p.remotes
+	// Remote lists are never processed themselves, so they are populated here
+	remote.populateOut()
+	p.remotes[name] = remote
+	return p
+}
+
+// SplitRemote - return remote name and list name of qualified include.
+// Return false if include has no remote part
+func SplitRemote(include string) (remote string, name string, ok bool) {
+	index := strings.Index(include, RemoteSeparator)
+	if index == -1 {
+		return "", include, false
+	}
+	return include[:index], include[index+len(RemoteSeparator):], true
+}
+
+// resolve - find included list among own lists or lists of remotes.
+// Return process the list belongs to
+func (p *Process) resolve(include string) (*Process, *c1ews.ListResponse, error) {
+	if remoteName, name, ok := SplitRemote(include); ok {
+		if remote, found := p.remotes[remoteName]; found {
+			remote.kind = p.kind
+			l, err := remote.FindListWithError(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s%w", remote.prefix, err)
+			}
+			return remote, l, nil
+		}
+	}
+	l, err := p.FindListWithError(include)
+	return p, l, err
+}
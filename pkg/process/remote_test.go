@@ -0,0 +1,87 @@
+//////////////////////////////////////////////////////////////////////////
+//
+//  (c) TMList 2023 by Mikhail Kondrashin (mkondrashin@gmail.com)
+//  Copyright under MIT Lincese. Please see LICENSE file for details
+//
+//  remote_test.go - tests for includes of other managers lists
+//
+//////////////////////////////////////////////////////////////////////////
+
+package process
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mpkondrashin/tmlist/pkg/c1ews"
+)
+
+func TestSplitRemote(t *testing.T) {
+	testCases := []struct {
+		include string
+		remote  string
+		name    string
+		ok      bool
+	}{
+		{"Windows", "", "Windows", false},
+		{"golden/Windows", "golden", "Windows", true},
+		{"golden/C:/Temp", "golden", "C:/Temp", true},
+	}
+	for _, tc := range testCases {
+		remote, name, ok := SplitRemote(tc.include)
+		if remote != tc.remote || name != tc.name || ok != tc.ok {
+			t.Errorf("%s: %s, %s, %v", tc.include, remote, name, ok)
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	golden := []c1ews.ListResponse{
+		{Name: "Base", Items: []string{"1"}},
+		{Name: "Standard", Description: "include: Base", Items: []string{"old"}},
+	}
+	in := []c1ews.ListResponse{
+		{Name: "Local", Items: []string{"2"}},
+		{Name: "Tenant", Description: "include: golden/Standard\ninclude: Local"},
+		{Name: "other/List", Items: []string{"3"}},
+		{Name: "Slash", Description: "include: other/List"},
+	}
+	p := NewProcess(in).SetRemote("golden", golden)
+	if err := p.Process(); err != nil {
+		t.Fatal(err)
+	}
+	if actual := p.FindList("Tenant").Items; !reflect.DeepEqual(actual, []string{"1", "2"}) {
+		t.Errorf("remote include: %v", actual)
+	}
+	if actual := p.FindList("Slash").Items; !reflect.DeepEqual(actual, []string{"3"}) {
+		t.Errorf("local list with slash: %v", actual)
+	}
+	var changed []string
+	_ = p.IterateChanged(func(l *c1ews.ListResponse) error {
+		changed = append(changed, l.Name)
+		return nil
+	})
+	if !reflect.DeepEqual(changed, []string{"Local", "Tenant", "other/List", "Slash"}) {
+		t.Errorf("changed lists: %v", changed)
+	}
+}
+
+func TestRemoteErrors(t *testing.T) {
+	in := []c1ews.ListResponse{
+		{Name: "Tenant", Description: "include: golden/Missing"},
+	}
+	p := NewProcess(in).SetRemote("golden", []c1ews.ListResponse{{Name: "Base"}})
+	if err := p.Process(); !errors.Is(err, ErrListNotFound) {
+		t.Errorf("error %v instead of %v", err, ErrListNotFound)
+	}
+	in = []c1ews.ListResponse{
+		{Name: "Tenant", Description: "include: golden/A"},
+	}
+	p = NewProcess(in).
+		SetRemote("golden", []c1ews.ListResponse{{Name: "A", Description: "include: other/B"}}).
+		SetRemote("other", []c1ews.ListResponse{{Name: "B", Description: "include: golden/A"}})
+	if err := p.Process(); !errors.Is(err, ErrCycleDependence) {
+		t.Errorf("error %v instead of %v", err, ErrCycleDependence)
+	}
+}